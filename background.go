@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	MAX_BACKGROUND_RUNS   = 50          // Oldest runs are removed from the history after this
+	MAX_BACKGROUND_OUTPUT = 1024 * 1024 // Output captured per run. Anything more is discarded
+)
+
+var backgroundRuns = NewBackgroundRuns()
+
+/*
+RunOutput captures stdout and stderr for a background run.
+*/
+type RunOutput struct {
+	mu        sync.Mutex
+	sb        strings.Builder
+	truncated bool
+}
+
+/*
+BackgroundRun is a single run of a 'background' action.
+*/
+type BackgroundRun struct {
+	mu      sync.Mutex
+	action  *MultipleActionData
+	output  *RunOutput
+	start   time.Time
+	end     time.Time
+	rc      int
+	running bool
+}
+
+type BackgroundRuns struct {
+	mu   sync.Mutex
+	runs []*BackgroundRun // Most recent first
+}
+
+func (ro *RunOutput) Write(p []byte) (int, error) {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	if ro.truncated {
+		return len(p), nil
+	}
+	if ro.sb.Len()+len(p) > MAX_BACKGROUND_OUTPUT {
+		ro.sb.Write(p[:MAX_BACKGROUND_OUTPUT-ro.sb.Len()])
+		ro.sb.WriteString(fmt.Sprintf("\n** Output truncated at %d bytes **\n", MAX_BACKGROUND_OUTPUT))
+		ro.truncated = true
+		return len(p), nil
+	}
+	return ro.sb.Write(p)
+}
+
+func (ro *RunOutput) String() string {
+	ro.mu.Lock()
+	defer ro.mu.Unlock()
+	return ro.sb.String()
+}
+
+func NewBackgroundRuns() *BackgroundRuns {
+	return &BackgroundRuns{runs: make([]*BackgroundRun, 0)}
+}
+
+/*
+Add a new running entry to the front of the history
+*/
+func (br *BackgroundRuns) Add(action *MultipleActionData) *BackgroundRun {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	run := &BackgroundRun{action: action, output: &RunOutput{}, start: time.Now(), rc: RC_OK, running: true}
+	br.runs = append([]*BackgroundRun{run}, br.runs...)
+	if len(br.runs) > MAX_BACKGROUND_RUNS {
+		br.runs = br.runs[:MAX_BACKGROUND_RUNS]
+	}
+	return run
+}
+
+/*
+A copy of the history, most recent first
+*/
+func (br *BackgroundRuns) List() []*BackgroundRun {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	resp := make([]*BackgroundRun, len(br.runs))
+	copy(resp, br.runs)
+	return resp
+}
+
+func (br *BackgroundRuns) Len() int {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+	return len(br.runs)
+}
+
+func (run *BackgroundRun) SetComplete(rc int) {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	run.end = time.Now()
+	run.rc = rc
+	run.running = false
+}
+
+func (run *BackgroundRun) Status() string {
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.running {
+		return "running"
+	}
+	switch run.rc {
+	case RC_OK:
+		return "complete"
+	case RC_CANCELLED:
+		return "cancelled"
+	case RC_TIMEOUT:
+		return "timeout"
+	}
+	return "failed"
+}
+
+func (run *BackgroundRun) String() string {
+	status := run.Status()
+	run.mu.Lock()
+	defer run.mu.Unlock()
+	if run.running {
+		return fmt.Sprintf("%s %s start:%s", run.action.name, status, FormatScheduleTime(run.start))
+	}
+	return fmt.Sprintf("%s %s start:%s end:%s rc:%d", run.action.name, status, FormatScheduleTime(run.start), FormatScheduleTime(run.end), run.rc)
+}
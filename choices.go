@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+Set the choices for the value. Either a static list or a memory value (with an optional filter)
+where each line selected by the filter is a choice. E.g. "memory:branches|origin/"
+*/
+func (lv *LocalValue) SetChoices(choices []string, choicesDef string) {
+	lv.choices = choices
+	lv.choicesDef = choicesDef
+}
+
+func (lv *LocalValue) HasChoices() bool {
+	return len(lv.choices) > 0 || lv.choicesDef != ""
+}
+
+/*
+Returns true if the value has no choices or 's' is one of them. Choices from a memory
+value are not known until they are read (see updateChoices).
+*/
+func (lv *LocalValue) IsChoice(s string) bool {
+	if len(lv.choices) == 0 {
+		return true
+	}
+	for _, c := range lv.choices {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+Read the choices from the memory value. Static choices are not changed.
+*/
+func (dc *DataCache) updateChoices(lv *LocalValue) error {
+	if lv.choicesDef == "" {
+		return nil
+	}
+	def, _, _ := PrefixMatch(lv.choicesDef, MEMORY_PREF, MEM_TYPE)
+	name, filter := splitNameFilter(def)
+	cw := dc.GetCacheWriter(name)
+	if cw == nil {
+		return fmt.Errorf("choices for '%s'. Memory value '%s' has not been set", lv.name, name)
+	}
+	fc, err := NewFilterChain(filter)
+	if err != nil {
+		return fmt.Errorf("choices for '%s'. %s", lv.name, err.Error())
+	}
+	var lines []string
+	if fc.IsLineFilter() {
+		lf, _ := NewLineFilter(filter)
+		//
+		// Filter each line on its own so each selected line is a choice even if the filter has no suffix
+		//
+		lines = make([]string, 0)
+		for _, line := range strings.Split(cw.GetContent(), "\n") {
+			var sb strings.Builder
+			lf.Line(line, &sb)
+			lines = append(lines, sb.String())
+		}
+	} else {
+		b, err := fc.Apply([]byte(cw.GetContent()))
+		if err != nil {
+			return fmt.Errorf("choices for '%s'. %s", lv.name, err.Error())
+		}
+		lines = strings.Split(string(b), "\n")
+	}
+	choices := make([]string, 0)
+	for _, line := range lines {
+		choice := strings.TrimSpace(line)
+		if choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+	if len(choices) == 0 {
+		return fmt.Errorf("choices for '%s'. Memory value '%s' does not contain any choices", lv.name, name)
+	}
+	lv.choices = choices
+	return nil
+}
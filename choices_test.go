@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStaticChoices(t *testing.T) {
+	dc := NewDataCache()
+	lv := dc.AddLocalValue("env", "Environment", "", 1, false, false, false, true)
+	if !lv.IsChoice("anything") {
+		t.Fatalf("[Choices:1.0]: Without choices any value is valid")
+	}
+	lv.SetChoices([]string{"dev", "prod"}, "")
+	if !lv.IsChoice("prod") || lv.IsChoice("test") || lv.IsChoice("") {
+		t.Fatalf("[Choices:1.1]: Only 'dev' or 'prod' are valid")
+	}
+	if err := dc.updateChoices(lv); err != nil || len(lv.choices) != 2 {
+		t.Fatalf("[Choices:1.2]: Static choices should not change. Got %v %v", lv.choices, err)
+	}
+}
+
+func TestMemoryChoices(t *testing.T) {
+	dc := NewDataCache()
+	lv := dc.AddLocalValue("branch", "Branch", "", 1, false, false, false, true)
+	lv.SetChoices(nil, "memory:branches|origin/")
+	err := dc.updateChoices(lv)
+	if err == nil || !strings.Contains(err.Error(), "'branches' has not been set") {
+		t.Fatalf("[Choices:2.0]: Should return not set error. Got %v", err)
+	}
+	cw, _ := NewCacheWriter("branches", MEM_TYPE)
+	cw.Write([]byte("  origin/main\n  origin/dev\n* local\n"))
+	dc.PutCacheWriter(cw)
+	err = dc.updateChoices(lv)
+	if err != nil {
+		t.Fatalf("[Choices:2.1]: Should not return error %s", err.Error())
+	}
+	if strings.Join(lv.choices, ",") != "origin/main,origin/dev" {
+		t.Fatalf("[Choices:2.2]: Filtered choices are wrong. Got %v", lv.choices)
+	}
+	if !lv.IsChoice("origin/dev") || lv.IsChoice("local") {
+		t.Fatalf("[Choices:2.3]: Only filtered lines are valid")
+	}
+
+	lv.SetChoices(nil, "memory:branches|nomatch")
+	err = dc.updateChoices(lv)
+	if err == nil || !strings.Contains(err.Error(), "does not contain any choices") {
+		t.Fatalf("[Choices:2.4]: Should return no choices error. Got %v", err)
+	}
+}
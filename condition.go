@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	COND_EXISTS_PREF = "exists:" // The condition is true if the file exists
+	COND_EQ          = "=="
+	COND_NE          = "!="
+)
+
+/*
+Evaluate an 'if' or 'unless' expression. Values are substituted first. The expression can be:
+
+	a == b         Strings are equal (after trimming spaces and quotes)
+	a != b         Strings are not equal
+	exists:path    The file or directory exists
+	value          true or yes is true. false, no or empty is false
+*/
+func EvaluateCondition(expr string, entryDialog func(*LocalValue) error, dataCache *DataCache) (bool, error) {
+	s, err := dataCache.Template(expr, entryDialog)
+	if err != nil {
+		return false, err
+	}
+	return evaluateConditionValue(expr, s)
+}
+
+/*
+Evaluate the expression after values have been substituted
+*/
+func evaluateConditionValue(expr, s string) (bool, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, COND_EXISTS_PREF) {
+		_, err := os.Stat(strings.TrimSpace(s[len(COND_EXISTS_PREF):]))
+		return err == nil, nil
+	}
+	if l, r, found := strings.Cut(s, COND_NE); found {
+		return conditionValue(l) != conditionValue(r), nil
+	}
+	if l, r, found := strings.Cut(s, COND_EQ); found {
+		return conditionValue(l) == conditionValue(r), nil
+	}
+	switch strings.ToLower(conditionValue(s)) {
+	case "true", "yes":
+		return true, nil
+	case "false", "no", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("condition '%s' (%s) is not true, false or a comparison", expr, s)
+}
+
+func conditionValue(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 1 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+/*
+Returns true if the step should run. An 'if' expression must be true and an
+'unless' expression must be false.
+*/
+func (sa *SingleAction) ShouldRun(entryDialog func(*LocalValue) error, dataCache *DataCache) (bool, string, error) {
+	if sa.ifExp != "" {
+		ok, err := EvaluateCondition(sa.ifExp, entryDialog, dataCache)
+		if err != nil {
+			return false, "", err
+		}
+		if !ok {
+			return false, fmt.Sprintf("if '%s' is false", sa.ifExp), nil
+		}
+	}
+	if sa.unlessExp != "" {
+		ok, err := EvaluateCondition(sa.unlessExp, entryDialog, dataCache)
+		if err != nil {
+			return false, "", err
+		}
+		if ok {
+			return false, fmt.Sprintf("unless '%s' is true", sa.unlessExp), nil
+		}
+	}
+	return true, "", nil
+}
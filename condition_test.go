@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConditionCompare(t *testing.T) {
+	dc := NewDataCache()
+	dc.AddLocalValue("branch", "Branch", "main", 1, false, false, false, false)
+	dc.SetSystemValue(SYS_RC, "1")
+	testCondition(t, dc, "%{branch} == main", true, "Cond:1.0")
+	testCondition(t, dc, "%{branch}==main", true, "Cond:1.1")
+	testCondition(t, dc, "%{branch} == 'main'", true, "Cond:1.2")
+	testCondition(t, dc, "%{branch} == dev", false, "Cond:1.3")
+	testCondition(t, dc, "%{branch} != dev", true, "Cond:1.4")
+	testCondition(t, dc, "%{_rc} != 0", true, "Cond:1.5")
+	testCondition(t, dc, "%{_rc} == 0", false, "Cond:1.6")
+	testCondition(t, dc, "%{undefined} == \"\"", false, "Cond:1.7")
+}
+
+func TestConditionValue(t *testing.T) {
+	dc := NewDataCache()
+	dc.AddLocalValue("yes", "Yes", "Yes", 1, false, false, false, false)
+	testCondition(t, dc, "%{yes}", true, "Value:1.0")
+	testCondition(t, dc, "true", true, "Value:1.1")
+	testCondition(t, dc, "false", false, "Value:1.2")
+	testCondition(t, dc, "", false, "Value:1.3")
+	_, err := EvaluateCondition("maybe", nil, dc)
+	if err == nil || !strings.Contains(err.Error(), "is not true, false or a comparison") {
+		t.Fatalf("[Value:1.4]: Should return an error. Got %v", err)
+	}
+}
+
+func TestConditionExists(t *testing.T) {
+	dc := NewDataCache()
+	dc.AddLocalValue("file", "File", "condition.go", 1, false, false, false, false)
+	testCondition(t, dc, "exists:%{file}", true, "Exists:1.0")
+	testCondition(t, dc, "exists:%{file}.notThere", false, "Exists:1.1")
+}
+
+func TestConditionShouldRun(t *testing.T) {
+	dc := NewDataCache()
+	sa := NewSingleAction("echo", []string{}, "", "", "", "", "", "", "true", "exists:condition.go", 0, 0, false)
+	run, reason, err := sa.ShouldRun(nil, dc)
+	if err != nil || run {
+		t.Fatalf("[Run:1.0]: Should not run. run:%t err:%v", run, err)
+	}
+	if reason != "unless 'exists:condition.go' is true" {
+		t.Fatalf("[Run:1.1]: Incorrect reason '%s'", reason)
+	}
+	sa = NewSingleAction("echo", []string{}, "", "", "", "", "", "", "true", "", 0, 0, false)
+	run, _, err = sa.ShouldRun(nil, dc)
+	if err != nil || !run {
+		t.Fatalf("[Run:1.2]: Should run. run:%t err:%v", run, err)
+	}
+}
+
+func testCondition(t *testing.T, dc *DataCache, expr string, exp bool, info string) {
+	act, err := EvaluateCondition(expr, nil, dc)
+	if err != nil {
+		t.Fatalf("[%s]: Should not return error %s", info, err.Error())
+	}
+	if act != exp {
+		t.Fatalf("[%s]: '%s' Actual:%t != Expected:%t", info, expr, act, exp)
+	}
+}
+
+func TestConditionStepValues(t *testing.T) {
+	dc := NewDataCache()
+	start := time.Date(2022, time.August, 10, 10, 30, 15, 0, time.Local)
+	setStepSystemValues("A", 2, 3, start, 1500*time.Millisecond, dc)
+	testCondition(t, dc, "%{_rc} == 3", true, "Step:1.0")
+	testCondition(t, dc, "%{_last.rc} == 3", true, "Step:1.1")
+	testCondition(t, dc, "%{A.step2.rc} == 3", true, "Step:1.2")
+	testCondition(t, dc, "%{_last.durationMs} == 1500", true, "Step:1.3")
+	testCondition(t, dc, "%{A.step2.start} == 2022-08-10 10:30:15", true, "Step:1.4")
+	setStepSystemValues("B", 0, 0, start, 0, dc)
+	testCondition(t, dc, "%{_rc} == 0", true, "Step:1.5")
+	testCondition(t, dc, "%{A.step2.rc} == 3", true, "Step:1.6")
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	MAX_CONSOLE_LINES    = 1000 // Oldest lines are discarded after this
+	CONSOLE_REFRESH_MS   = 300  // How often the console view checks for new output
+	CONSOLE_BOTTOM_SLACK = 5    // Pixels from the bottom of the console that still count as at the bottom
+)
+
+var consoleColour = []color.Color{color.NRGBA{R: 0, G: 160, B: 0, A: 255}, color.NRGBA{R: 220, G: 0, B: 0, A: 255}}
+
+/*
+ConsoleLine is a single line of output from an action
+*/
+type ConsoleLine struct {
+	action string
+	stream int // STD_OUT or STD_ERR
+	text   string
+}
+
+/*
+Console holds the most recent lines of output from all actions in a ring buffer.
+*/
+type Console struct {
+	mu      sync.Mutex
+	lines   []*ConsoleLine
+	head    int               // Index of the oldest line
+	count   int               // Number of lines in the buffer
+	partial map[string]string // Text waiting for a new line. Keyed by action and stream
+	dirty   bool              // Changed since TakeDirty was last called
+}
+
+/*
+ConsoleWriter writes an action's stdout or stderr to the Console
+*/
+type ConsoleWriter struct {
+	console  *Console
+	action   string
+	stream   int
+	terminal bool // Also write to the terminal (with colour prefix)
+}
+
+func NewConsole(max int) *Console {
+	return &Console{lines: make([]*ConsoleLine, max), head: 0, count: 0, partial: make(map[string]string), dirty: false}
+}
+
+func NewConsoleWriter(console *Console, action string, stream int, terminal bool) *ConsoleWriter {
+	return &ConsoleWriter{console: console, action: action, stream: stream, terminal: terminal}
+}
+
+func (cw *ConsoleWriter) Write(p []byte) (int, error) {
+	if cw.terminal {
+		fmt.Printf("%s%s%s", stdColourPrefix[cw.stream], string(p), RESET)
+	}
+	cw.console.Append(cw.action, cw.stream, p)
+	return len(p), nil
+}
+
+func (c *ConsoleLine) String() string {
+	return fmt.Sprintf("[%s] %s", c.action, c.text)
+}
+
+/*
+Add text to the console. Only complete lines are added. Any trailing text is held
+until the rest of the line arrives.
+*/
+func (c *Console) Append(action string, stream int, p []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := fmt.Sprintf("%d:%s", stream, action)
+	text := c.partial[key] + string(p)
+	parts := strings.Split(text, "\n")
+	for _, l := range parts[:len(parts)-1] {
+		c.add(&ConsoleLine{action: action, stream: stream, text: strings.TrimRight(l, "\r")})
+	}
+	c.partial[key] = parts[len(parts)-1]
+	c.dirty = true
+}
+
+func (c *Console) add(line *ConsoleLine) {
+	max := len(c.lines)
+	if c.count < max {
+		c.lines[(c.head+c.count)%max] = line
+		c.count++
+	} else {
+		c.lines[c.head] = line
+		c.head = (c.head + 1) % max
+	}
+}
+
+/*
+The lines in the order they were written. Text waiting for a new line is included.
+*/
+func (c *Console) Lines() []*ConsoleLine {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp := make([]*ConsoleLine, 0, c.count+len(c.partial))
+	for i := 0; i < c.count; i++ {
+		resp = append(resp, c.lines[(c.head+i)%len(c.lines)])
+	}
+	for key, text := range c.partial {
+		if text == "" {
+			continue
+		}
+		stream, action, _ := strings.Cut(key, ":")
+		if stream == fmt.Sprintf("%d", STD_ERR) {
+			resp = append(resp, &ConsoleLine{action: action, stream: STD_ERR, text: text})
+		} else {
+			resp = append(resp, &ConsoleLine{action: action, stream: STD_OUT, text: text})
+		}
+	}
+	return resp
+}
+
+func (c *Console) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.head = 0
+	c.count = 0
+	c.partial = make(map[string]string)
+	c.dirty = true
+}
+
+/*
+Returns true if the console has changed since the last call.
+*/
+func (c *Console) TakeDirty() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	d := c.dirty
+	c.dirty = false
+	return d
+}
+
+func (c *Console) String() string {
+	var sb strings.Builder
+	for _, l := range c.Lines() {
+		sb.WriteString(l.String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+/*
+The console view. The lines are in a scroll container that is updated by Update.
+*/
+type ConsolePanel struct {
+	lines   *fyne.Container
+	scroll  *container.Scroll
+	content fyne.CanvasObject
+}
+
+func NewConsolePanel(console *Console) *ConsolePanel {
+	tb := container.NewHBox()
+	tb.Add(widget.NewButtonWithIcon("Clear", theme.ContentClearIcon(), func() {
+		console.Clear()
+		refresh()
+	}))
+	tb.Add(widget.NewButtonWithIcon("Copy", theme.ContentCopyIcon(), func() {
+		mainWindow.Clipboard().SetContent(console.String())
+	}))
+	tb.Add(widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), func() {
+		SaveConsoleDialog(console, mainWindow)
+	}))
+	cp := &ConsolePanel{lines: container.NewVBox()}
+	cp.setLines(console)
+	cp.scroll = container.NewScroll(cp.lines)
+	cp.scroll.ScrollToBottom()
+	cp.content = container.NewBorder(container.NewVBox(tb, widget.NewSeparator()), nil, nil, nil, cp.scroll)
+	return cp
+}
+
+func (cp *ConsolePanel) setLines(console *Console) {
+	objects := make([]fyne.CanvasObject, 0)
+	for _, l := range console.Lines() {
+		t := canvas.NewText(l.String(), consoleColour[l.stream])
+		t.TextStyle = fyne.TextStyle{Monospace: true}
+		objects = append(objects, t)
+	}
+	cp.lines.Objects = objects
+	cp.lines.Refresh()
+}
+
+/*
+Show the new console lines. Scroll to the new output only if the view was at the bottom
+so the user can read earlier output while an action is running.
+*/
+func (cp *ConsolePanel) Update(console *Console) {
+	atBottom := cp.scroll.Offset.Y >= cp.lines.MinSize().Height-cp.scroll.Size().Height-CONSOLE_BOTTOM_SLACK
+	cp.setLines(console)
+	if atBottom {
+		cp.scroll.ScrollToBottom()
+	} else {
+		cp.scroll.Refresh()
+	}
+}
+
+/*
+Keep the console view up to date while actions are writing to it. Only the console lines are
+updated so the user can scroll back through the output.
+Updates are limited to one every CONSOLE_REFRESH_MS.
+*/
+func refreshConsole() {
+	for {
+		time.Sleep(CONSOLE_REFRESH_MS * time.Millisecond)
+		if outputConsole.TakeDirty() && mainWindowActive {
+			cp := getConsoleView()
+			if cp != nil {
+				cp.Update(outputConsole)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestConsolePartialLines(t *testing.T) {
+	c := NewConsole(10)
+	c.Append("a1", STD_OUT, []byte("line 1\nline"))
+	c.Append("a2", STD_ERR, []byte("err 1\r\n"))
+	testConsoleLines(t, c, "[a1] line 1|[a2] err 1|[a1] line", "Partial:1.0")
+	c.Append("a1", STD_OUT, []byte(" 2\n"))
+	testConsoleLines(t, c, "[a1] line 1|[a2] err 1|[a1] line 2", "Partial:1.1")
+	if c.Lines()[1].stream != STD_ERR {
+		t.Fatalf("[Partial:1.2]: Line 2 should be STD_ERR")
+	}
+}
+
+func TestConsoleRingBuffer(t *testing.T) {
+	c := NewConsole(3)
+	for i := 1; i <= 5; i++ {
+		c.Append("a", STD_OUT, []byte(fmt.Sprintf("%d\n", i)))
+	}
+	testConsoleLines(t, c, "[a] 3|[a] 4|[a] 5", "Ring:1.0")
+	if !c.TakeDirty() {
+		t.Fatalf("[Ring:1.1]: Should be dirty")
+	}
+	if c.TakeDirty() {
+		t.Fatalf("[Ring:1.2]: Should not be dirty")
+	}
+	c.Clear()
+	testConsoleLines(t, c, "", "Ring:1.3")
+	c.Append("a", STD_OUT, []byte("6\n"))
+	testConsoleLines(t, c, "[a] 6", "Ring:1.4")
+}
+
+func testConsoleLines(t *testing.T, c *Console, exp, info string) {
+	act := ""
+	for i, l := range c.Lines() {
+		if i > 0 {
+			act = act + "|"
+		}
+		act = act + l.String()
+	}
+	if act != exp {
+		t.Fatalf("[%s]: Actual:'%s' != Expected:'%s'", info, act, exp)
+	}
+}
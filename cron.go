@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const CRON_SEARCH_YEARS = 5 // Give up looking for the next run time after this many years
+
+// CronSchedule is a standard 5 field cron expression:
+//
+//	minute(0-59) hour(0-23) day-of-month(1-31) month(1-12) day-of-week(0-6, 0 or 7 = Sunday)
+//
+// Each field can be '*', a number, a range 'a-b', a step '*/n' or 'a-b/n' or a comma separated list of these.
+type CronSchedule struct {
+	spec    string
+	minute  uint64
+	hour    uint64
+	dom     uint64
+	month   uint64
+	dow     uint64
+	domStar bool // day-of-month was '*'
+	dowStar bool // day-of-week was '*'
+}
+
+type cronField struct {
+	name string
+	min  int
+	max  int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day-of-month", 1, 31},
+	{"month", 1, 12},
+	{"day-of-week", 0, 7},
+}
+
+func ParseCron(spec string) (*CronSchedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression '%s' must have %d fields", spec, len(cronFields))
+	}
+	bits := make([]uint64, len(cronFields))
+	for i, p := range parts {
+		b, err := parseCronField(p, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression '%s'. %s", spec, err.Error())
+		}
+		bits[i] = b
+	}
+	//
+	// Sunday can be 0 or 7
+	//
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = (bits[4] | 1) &^ (1 << 7)
+	}
+	return &CronSchedule{spec: spec, minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4], domStar: parts[2] == "*", dowStar: parts[4] == "*"}, nil
+}
+
+func parseCronField(field string, def cronField) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rng := item
+		step := 1
+		var err error
+		if i := strings.Index(item, "/"); i >= 0 {
+			rng = item[:i]
+			step, err = strconv.Atoi(item[i+1:])
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step '%s' in %s field", item[i+1:], def.name)
+			}
+		}
+		lo, hi := def.min, def.max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			lo, err = strconv.Atoi(from)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value '%s' in %s field", from, def.name)
+			}
+			hi = lo
+			if isRange {
+				hi, err = strconv.Atoi(to)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value '%s' in %s field", to, def.name)
+				}
+			} else if step > 1 {
+				hi = def.max
+			}
+		}
+		if lo < def.min || hi > def.max || lo > hi {
+			return 0, fmt.Errorf("value '%s' out of range %d-%d in %s field", item, def.min, def.max, def.name)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+/*
+Return the first time after 't' that matches the schedule. Seconds are ignored.
+Returns a zero time if there is no match in the next CRON_SEARCH_YEARS years.
+*/
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(CRON_SEARCH_YEARS, 0, 0)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+/*
+As with standard cron, if both day fields are restricted then either can match.
+*/
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+func (c *CronSchedule) String() string {
+	return c.spec
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var cronFrom = time.Date(2022, time.August, 10, 10, 30, 15, 0, time.Local) // A Wednesday
+
+func TestCronParseErrors(t *testing.T) {
+	testCronErr(t, "* * * *", "must have 5 fields", "Err:1.0")
+	testCronErr(t, "60 * * * *", "out of range 0-59 in minute field", "Err:1.1")
+	testCronErr(t, "* 5-2 * * *", "out of range 0-23 in hour field", "Err:1.2")
+	testCronErr(t, "* * 0 * *", "out of range 1-31 in day-of-month field", "Err:1.3")
+	testCronErr(t, "*/0 * * * *", "invalid step '0' in minute field", "Err:1.4")
+	testCronErr(t, "a * * * *", "invalid value 'a' in minute field", "Err:1.5")
+}
+
+func TestCronNext(t *testing.T) {
+	testCronNext(t, "* * * * *", "2022-08-10 10:31", "Next:1.0")
+	testCronNext(t, "*/15 * * * *", "2022-08-10 10:45", "Next:1.1")
+	testCronNext(t, "0 * * * *", "2022-08-10 11:00", "Next:1.2")
+	testCronNext(t, "0 9 * * *", "2022-08-11 09:00", "Next:1.3")
+	testCronNext(t, "0 9 * * 1-5", "2022-08-11 09:00", "Next:1.4")
+	testCronNext(t, "0 9 * * 0", "2022-08-14 09:00", "Next:1.5")
+	testCronNext(t, "0 9 * * 7", "2022-08-14 09:00", "Next:1.6")
+	testCronNext(t, "0 0 1 * *", "2022-09-01 00:00", "Next:1.7")
+	testCronNext(t, "0 0 1 1 *", "2023-01-01 00:00", "Next:1.8")
+	testCronNext(t, "5,35 10 * * *", "2022-08-10 10:35", "Next:1.9")
+	testCronNext(t, "10-20/5 11 * * *", "2022-08-10 11:10", "Next:1.10")
+	testCronNext(t, "0 0 31 * 1", "2022-08-15 00:00", "Next:1.11")
+	testCronNext(t, "0 0 29 2 *", "2024-02-29 00:00", "Next:1.12")
+}
+
+func TestCronNever(t *testing.T) {
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("Never:1.0 Should not return error %s", err.Error())
+	}
+	if !c.Next(cronFrom).IsZero() {
+		t.Fatalf("Never:1.1 Should return a zero time not %s", c.Next(cronFrom))
+	}
+}
+
+func testCronNext(t *testing.T, spec, exp, info string) {
+	c, err := ParseCron(spec)
+	if err != nil {
+		t.Fatalf("[%s]: Should not return error %s", info, err.Error())
+	}
+	act := c.Next(cronFrom).Format("2006-01-02 15:04")
+	if act != exp {
+		t.Fatalf("[%s]: Actual:'%s' != Expected:'%s'", info, act, exp)
+	}
+}
+
+func testCronErr(t *testing.T, spec, exp, info string) {
+	_, err := ParseCron(spec)
+	if err == nil {
+		t.Fatalf("[%s]: Should return error '%s'", info, exp)
+	}
+	if !strings.Contains(err.Error(), exp) {
+		t.Fatalf("[%s]: Actual Error:'%s' != Expected Error:'%s'", info, err.Error(), exp)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	FILTER_CSV_PREF   = "csv:"    // Select columns from CSV output. E.g. csv:0,2 or csv:name,email
+	FILTER_TSV_PREF   = "tsv:"    // Select columns from tab separated output. E.g. tsv:1
+	FILTER_CSV_HEADER = ":header" // The first row is a header and is not output. E.g. csv:0,2:header
+)
+
+/*
+A csv: or tsv: filter stage. The columns are separated by ',' and are either a ZERO based
+index (a negative index counts back from the last column) or a name in the header row.
+Quoted column names can contain a ','. E.g. csv:"last, first",email
+
+The first row is the header if a column is selected by name or the columns are followed by
+:header. The header row is not output. No columns (E.g. csv::header) selects all of them.
+
+A single column is output as it is. More than one column is output as a row in the same format
+as the input so quoted CSV values stay quoted. A column that is not in a row is empty.
+*/
+func newCsvStage(sel string) (func([]byte) ([]byte, error), error) {
+	tsv := strings.HasPrefix(sel, FILTER_TSV_PREF)
+	spec := sel[len(FILTER_CSV_PREF):]
+	header := strings.HasSuffix(spec, FILTER_CSV_HEADER)
+	if header {
+		spec = strings.TrimSuffix(spec, FILTER_CSV_HEADER)
+	}
+	cols := make([]string, 0)
+	if spec != "" {
+		r := csv.NewReader(strings.NewReader(spec))
+		c, err := r.Read()
+		if err != nil {
+			return nil, fmt.Errorf("filter '%s' columns are invalid. %s", sel, err.Error())
+		}
+		cols = c
+	}
+	for _, c := range cols {
+		if c == "" {
+			return nil, fmt.Errorf("filter '%s' has an empty column", sel)
+		}
+		if _, err := strconv.Atoi(c); err != nil {
+			header = true
+		}
+	}
+	return func(input []byte) ([]byte, error) {
+		rows, err := csvRows(sel, input, tsv)
+		if err != nil {
+			return nil, err
+		}
+		var sb strings.Builder
+		w := csv.NewWriter(&sb)
+		var indexes []int
+		for i, row := range rows {
+			if i == 0 {
+				indexes, err = csvColumnIndexes(sel, cols, row)
+				if err != nil {
+					return nil, err
+				}
+				if header {
+					continue
+				}
+			}
+			fields := csvFields(row, cols, indexes)
+			switch {
+			case len(fields) == 1:
+				sb.WriteString(fields[0])
+				sb.WriteString("\n")
+			case tsv:
+				sb.WriteString(strings.Join(fields, "\t"))
+				sb.WriteString("\n")
+			default:
+				w.Write(fields)
+				w.Flush()
+			}
+		}
+		return []byte(sb.String()), nil
+	}, nil
+}
+
+/*
+Read the rows of the output. TSV has no quoting so each line is split at each tab and a '"'
+is part of the value. Blank lines are skipped.
+*/
+func csvRows(sel string, input []byte, tsv bool) ([][]string, error) {
+	if tsv {
+		rows := make([][]string, 0)
+		lines, _ := splitLines(string(input))
+		for _, l := range lines {
+			l = strings.TrimSuffix(l, "\r")
+			if l != "" {
+				rows = append(rows, strings.Split(l, "\t"))
+			}
+		}
+		return rows, nil
+	}
+	r := csv.NewReader(strings.NewReader(string(input)))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("filter '%s' input is invalid. %s", sel, err.Error())
+	}
+	return rows, nil
+}
+
+/*
+The index for each column. A negative index is kept so it counts from the end of each row.
+Names are found in the header row.
+*/
+func csvColumnIndexes(sel string, cols, headerRow []string) ([]int, error) {
+	indexes := make([]int, 0)
+	for _, c := range cols {
+		i, err := strconv.Atoi(c)
+		if err == nil {
+			indexes = append(indexes, i)
+			continue
+		}
+		found := -1
+		for hi, h := range headerRow {
+			if strings.TrimSpace(h) == c {
+				found = hi
+				break
+			}
+		}
+		if found < 0 {
+			return nil, fmt.Errorf("filter '%s' column '%s' is not in the header '%s'", sel, c, strings.Join(headerRow, ","))
+		}
+		indexes = append(indexes, found)
+	}
+	return indexes, nil
+}
+
+/*
+The selected fields from the row. All of them if no columns were given.
+*/
+func csvFields(row, cols []string, indexes []int) []string {
+	if len(cols) == 0 {
+		return row
+	}
+	fields := make([]string, 0)
+	for _, i := range indexes {
+		if i < 0 {
+			i = len(row) + i
+		}
+		if i >= 0 && i < len(row) {
+			fields = append(fields, row[i])
+		} else {
+			fields = append(fields, "")
+		}
+	}
+	return fields
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+)
+
+const tdCsv = "name,email,\"last, first\"\nstuart,\"s@x.com\",\"davies, stuart\"\nbob,b@x.com,\"smith, bob\"\n"
+const tdTsv = "name\temail\nstuart\ts@x.com\nbob\tb@x.com\n"
+
+func TestFilterCsv(t *testing.T) {
+	testCompleteResult(t, "name|csv:0", []string{tdCsv}, "name\nstuart\nbob\n", "Csv:1.0")
+	testCompleteResult(t, "name|csv:2:header", []string{tdCsv[:30], tdCsv[30:]}, "davies, stuart\nsmith, bob\n", "Csv:1.1")
+	testCompleteResult(t, "name|csv:email", []string{tdCsv}, "s@x.com\nb@x.com\n", "Csv:1.2")
+	testCompleteResult(t, "name|csv:\"last, first\",name", []string{tdCsv}, "\"davies, stuart\",stuart\n\"smith, bob\",bob\n", "Csv:1.3")
+	testCompleteResult(t, "name|csv:-1:header|head:1", []string{tdCsv}, "davies, stuart\n", "Csv:1.4")
+	testCompleteResult(t, "name|csv::header", []string{tdCsv}, "stuart,s@x.com,\"davies, stuart\"\nbob,b@x.com,\"smith, bob\"\n", "Csv:1.5")
+	testCompleteResult(t, "name|csv:0,5", []string{"a,b\nc\n"}, "a,\nc,\n", "Csv:1.6")
+	testCompleteResult(t, "name|csv:name:header|sort|join:,", []string{tdCsv}, "bob,stuart", "Csv:1.7")
+	testCompleteResult(t, "name|tsv:email", []string{tdTsv}, "s@x.com\nb@x.com\n", "Csv:1.8")
+	testCompleteResult(t, "name|tsv:1,0", []string{tdTsv}, "email\tname\ns@x.com\tstuart\nb@x.com\tbob\n", "Csv:1.9")
+	testCompleteResult(t, "name|tsv:1", []string{"1\tsays \"hi\"\n2\t\"quoted\"\r\n"}, "says \"hi\"\n\"quoted\"\n", "Csv:1.10")
+	testCompleteResult(t, "name|tsv:1,0", []string{"1\tsays \"hi\", bye\n"}, "says \"hi\", bye\t1\n", "Csv:1.11")
+
+	testCompleteResult(t, "name|csv:phone", []string{tdCsv}, "filter 'csv:phone' column 'phone' is not in the header 'name,email,last, first'", "Csv:2.0")
+	testCompleteResult(t, "name|csv:0,,1", []string{tdCsv}, "filter 'csv:0,,1' has an empty column", "Csv:2.1")
+	testCompleteResult(t, "name|csv:0", []string{"a,\"b\nc"}, "filter 'csv:0' input is invalid.", "Csv:2.2")
+
+	testPipeWriter(t, "csv:1:header", []string{tdCsv[:20], tdCsv[20:]}, "s@x.com\nb@x.com\n", "Csv:3.0")
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
 	"strings"
 	"sync"
@@ -22,6 +23,14 @@ type LocalValue struct {
 	isFileWatch   bool
 	inputDone     bool
 	inputRequired bool
+	persist       bool     // Save the value (and lastValue) in the state file. See state.go
+	choices       []string // If defined the value must be one of these. See choices.go
+	choicesDef    string   // memory:name|filter. The choices are read from the memory value before input
+	valueType     string   // One of VT_STRING, VT_INT... See valuetype.go
+	pattern       *regexp.Regexp
+	maxLen        int      // 0 is no maximum
+	min           *float64 // VT_INT only. nil is no minimum
+	max           *float64 // VT_INT only. nil is no maximum
 	notifyChannel chan *NotifyMessage
 }
 
@@ -30,10 +39,11 @@ type DataCache struct {
 	memoryMap   map[string]*CacheWriter
 	localVarMap map[string]*LocalValue
 	envMap      map[string]string
+	sysMap      map[string]string // Values set by gtool when commands run. E.g. _rc
 }
 
 func newLocalValue(name, desc, defaultVal string, minLen int, isPassword, isFileName, isFileWatch, inputRequired bool) *LocalValue {
-	return &LocalValue{name: name, desc: desc, _value: defaultVal, minLen: minLen, lastValue: "", isPassword: isPassword, isFileName: isFileName, isFileWatch: isFileWatch, inputDone: false, inputRequired: inputRequired}
+	return &LocalValue{name: name, desc: desc, _value: defaultVal, minLen: minLen, lastValue: "", isPassword: isPassword, isFileName: isFileName, isFileWatch: isFileWatch, inputDone: false, inputRequired: inputRequired, valueType: VT_STRING}
 }
 
 func (lv *LocalValue) String() string {
@@ -95,7 +105,7 @@ func NewDataCache() *DataCache {
 			m[pair[0]] = pair[1]
 		}
 	}
-	return &DataCache{memoryMap: make(map[string]*CacheWriter), localVarMap: make(map[string]*LocalValue), envMap: m}
+	return &DataCache{memoryMap: make(map[string]*CacheWriter), localVarMap: make(map[string]*LocalValue), envMap: m, sysMap: make(map[string]string)}
 }
 
 func (dc *DataCache) LogLocalValues(debugLog *LogData) {
@@ -133,6 +143,17 @@ func (dc *DataCache) GetEnvValueNamesSorted() []string {
 	return sl
 }
 
+func (dc *DataCache) GetSystemValueNamesSorted() []string {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	sl := make([]string, 0)
+	for n := range dc.sysMap {
+		sl = append(sl, n)
+	}
+	sort.Strings(sl)
+	return sl
+}
+
 func (dc *DataCache) GetLocalValue(name string) (*LocalValue, bool) {
 	lv, found := dc.localVarMap[name]
 	return lv, found
@@ -174,28 +195,87 @@ func (dc *DataCache) GetCacheWriter(name string) *CacheWriter {
 	return nil
 }
 
+func (dc *DataCache) SetSystemValue(name, value string) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.sysMap[name] = value
+}
+
+func (dc *DataCache) GetSystemValue(name string) (string, bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	s, found := dc.sysMap[name]
+	return s, found
+}
+
 func (dc *DataCache) Template(s string, dialogFunc func(*LocalValue) error) (string, error) {
+	return dc.template(s, dialogFunc, nil, false)
+}
+
+/*
+As Template but each substituted value is quoted (see ShellQuote) so it is a single word in a shell script
+*/
+func (dc *DataCache) ShellTemplate(s string, dialogFunc func(*LocalValue) error) (string, error) {
+	return dc.template(s, dialogFunc, ShellQuote, false)
+}
+
+/*
+As Template but values that have not been input yet are shown as <input:name> and
+passwords as <password:name>. Used to preview an action without running it.
+*/
+func (dc *DataCache) PreviewTemplate(s string, shell bool) string {
+	var quote func(string) string
+	if shell {
+		quote = ShellQuote
+	}
+	resp, err := dc.template(s, nil, quote, true)
+	if err != nil {
+		return s
+	}
+	return resp
+}
+
+func (dc *DataCache) template(s string, dialogFunc func(*LocalValue) error, quote func(string) string, placeholders bool) (string, error) {
+	if quote == nil {
+		quote = func(v string) string { return v }
+	}
 	return TemplateParse(s, func(name string) (string, error) {
 		if name == "" {
 			return "%{}", nil
 		}
 		cw := dc.GetCacheWriter(name)
 		if cw != nil {
-			return cw.GetContent(), nil
+			return quote(cw.GetContent()), nil
 		}
 		lv, found := dc.localVarMap[name]
+		if found && placeholders {
+			if lv.isPassword {
+				return fmt.Sprintf("<password:%s>", name), nil
+			}
+			if lv.inputRequired && !lv.inputDone {
+				return fmt.Sprintf("<input:%s>", name), nil
+			}
+		}
 		if found {
 			if !lv.inputDone && lv.inputRequired && dialogFunc != nil {
-				err := dialogFunc(lv)
+				err := dc.updateChoices(lv)
+				if err != nil {
+					return "", err
+				}
+				err = dialogFunc(lv)
 				if err != nil {
 					return "", err
 				}
 			}
-			return lv.GetValue(), nil
+			return quote(lv.GetValue()), nil
+		}
+		s, found := dc.GetSystemValue(name)
+		if found {
+			return quote(s), nil
 		}
-		s, found := dc.envMap[name]
+		s, found = dc.envMap[name]
 		if found {
-			return s, nil
+			return quote(s), nil
 		}
 		return fmt.Sprintf("%%{%s}", name), nil
 	})
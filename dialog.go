@@ -3,12 +3,14 @@ package main
 import (
 	"fmt"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
@@ -27,29 +29,42 @@ type MyDialog struct {
 	parent   fyne.Window
 	wait     bool
 	err      error
-	isValid  func(string, *LocalValue) bool
+	isValid  func(string, *LocalValue) error
+	reason   *widget.Label // Shows why the OK button is disabled
 }
 
-func newMyDialog(value *LocalValue, validate func(string, *LocalValue) bool, parentWindow fyne.Window, debugLog *LogData) *MyDialog {
+func newMyDialog(value *LocalValue, validate func(string, *LocalValue) error, parentWindow fyne.Window, debugLog *LogData) *MyDialog {
 	return &MyDialog{value: value, isValid: validate, parent: parentWindow, wait: true, err: nil, debugLog: debugLog}
 }
 
 func ValidatedEntryDialog(localValue *LocalValue) error {
-	return newMyDialog(localValue, func(s string, iv *LocalValue) bool {
-		return len(strings.TrimSpace(s)) >= iv.minLen
-	}, mainWindow, debugLogMain).runMyDialog(VALUE_DIALOG_TYPE).err
+	validate := func(s string, iv *LocalValue) error {
+		return iv.Validate(s)
+	}
+	if headlessMode {
+		return TerminalEntryDialog(localValue, validate, VALUE_DIALOG_TYPE)
+	}
+	return newMyDialog(localValue, validate, mainWindow, debugLogMain).runMyDialog(VALUE_DIALOG_TYPE).err
 }
 
 func SysInDialog(localValue *LocalValue) error {
-	return newMyDialog(localValue, func(s string, iv *LocalValue) bool {
-		return true
-	}, mainWindow, debugLogMain).runMyDialog(SYSIN_DIALOG_TYPE).err
+	validate := func(s string, iv *LocalValue) error {
+		return nil
+	}
+	if headlessMode {
+		return TerminalEntryDialog(localValue, validate, SYSIN_DIALOG_TYPE)
+	}
+	return newMyDialog(localValue, validate, mainWindow, debugLogMain).runMyDialog(SYSIN_DIALOG_TYPE).err
 }
 
 func SysOutDialog(localValue *LocalValue) error {
-	return newMyDialog(localValue, func(s string, iv *LocalValue) bool {
-		return true
-	}, mainWindow, debugLogMain).runMyDialog(SYSOUT_DIALOG_TYPE).err
+	validate := func(s string, iv *LocalValue) error {
+		return nil
+	}
+	if headlessMode {
+		return TerminalEntryDialog(localValue, validate, SYSOUT_DIALOG_TYPE)
+	}
+	return newMyDialog(localValue, validate, mainWindow, debugLogMain).runMyDialog(SYSOUT_DIALOG_TYPE).err
 }
 
 func WarnDialog(title, message, additional string, parentWindow fyne.Window, timeout int, debugLog *LogData) int {
@@ -92,6 +107,72 @@ func WarnDialog(title, message, additional string, parentWindow fyne.Window, tim
 	return rc
 }
 
+/*
+Ask the user a Yes/No question in a modal dialog. Blocks until a button is pressed.
+Returns true for Yes.
+*/
+func ConfirmDialog(title, message string, parentWindow fyne.Window, debugLog *LogData) bool {
+	wait := true
+	confirmed := false
+	yes := widget.NewButtonWithIcon("Yes", theme.ConfirmIcon(), func() {
+		wait = false
+		confirmed = true
+	})
+	yes.Importance = widget.HighImportance
+	no := widget.NewButtonWithIcon("No", theme.CancelIcon(), func() {
+		wait = false
+		confirmed = false
+	})
+	buttons := container.NewCenter(container.NewHBox(no, yes))
+	titleLab := container.NewCenter(widget.NewLabel(title))
+	messageLab := container.NewCenter(widget.NewLabel(message))
+
+	border := container.NewBorder(titleLab, buttons, nil, nil, messageLab)
+	popup := widget.NewModalPopUp(border, parentWindow.Canvas())
+	popup.Show()
+	for wait {
+		time.Sleep(200 * time.Millisecond)
+	}
+	popup.Hide()
+	if debugLog.IsLogging() {
+		debugLog.WriteLog(fmt.Sprintf("Confirm: \"%s\" confirmed:%t", message, confirmed))
+	}
+	return confirmed
+}
+
+/*
+Show the (read only) output of a command in a scrollable modal dialog.
+*/
+func OutputDialog(title, output string, parentWindow fyne.Window) {
+	if output == "" {
+		output = "-- No Output --"
+	}
+	content := container.NewScroll(widget.NewLabelWithStyle(output, fyne.TextAlignLeading, fyne.TextStyle{Monospace: true}))
+	d := dialog.NewCustom(title, "Close", content, parentWindow)
+	size := parentWindow.Canvas().Size()
+	d.Resize(fyne.NewSize(size.Width*0.9, size.Height*0.9))
+	d.Show()
+}
+
+/*
+Save the console output to a file chosen by the user
+*/
+func SaveConsoleDialog(console *Console, parentWindow fyne.Window) {
+	d := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+		if err == nil && uc != nil {
+			defer uc.Close()
+			_, err = uc.Write([]byte(console.String()))
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("failed to save console output. %s", err.Error()), parentWindow)
+		}
+	}, parentWindow)
+	d.SetFileName("gtool-console.txt")
+	size := parentWindow.Canvas().Size()
+	d.Resize(fyne.NewSize(size.Width*0.9, size.Height*0.9))
+	d.Show()
+}
+
 func (d *MyDialog) commit(s string) {
 	d.value.SetValue(s)
 	if d.debugLog.IsLogging() {
@@ -109,19 +190,27 @@ func (d *MyDialog) abort(s string) {
 	d.wait = false
 }
 
-func (d *MyDialog) callIsValid(s string) bool {
+func (d *MyDialog) callIsValid(s string) error {
 	if d.isValid == nil {
-		return true
+		return nil
 	}
 	return d.isValid(s, d.value)
 }
 
 func (d *MyDialog) onChange(s string, ok *widget.Button) {
-	if d.callIsValid(s) {
+	err := d.callIsValid(s)
+	if err == nil {
 		ok.Enable()
 	} else {
 		ok.Disable()
 	}
+	if d.reason != nil {
+		if err == nil {
+			d.reason.SetText("")
+		} else {
+			d.reason.SetText(err.Error())
+		}
+	}
 }
 
 func (d *MyDialog) runMyDialog(dt ENUM_ENTRY_TYPE) *MyDialog {
@@ -165,6 +254,12 @@ func (d *MyDialog) runMyDialog(dt ENUM_ENTRY_TYPE) *MyDialog {
 		}
 		return d
 	}
+	if d.value.HasChoices() {
+		return d.runChoiceDialog()
+	}
+	if d.value.IsBool() {
+		return d.runBoolDialog()
+	}
 
 	entry := widget.NewEntry()
 	if d.value.isPassword {
@@ -172,7 +267,7 @@ func (d *MyDialog) runMyDialog(dt ENUM_ENTRY_TYPE) *MyDialog {
 	}
 	entry.SetText(d.value.GetValue())
 	entry.OnSubmitted = func(s string) {
-		if d.callIsValid(entry.Text) {
+		if d.callIsValid(entry.Text) == nil {
 			d.commit(entry.Text)
 		}
 	}
@@ -186,6 +281,7 @@ func (d *MyDialog) runMyDialog(dt ENUM_ENTRY_TYPE) *MyDialog {
 		d.abort("input cancelled by user")
 	})
 
+	d.reason = widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
 	entry.OnChanged = func(s string) {
 		d.onChange(entry.Text, ok)
 	}
@@ -203,7 +299,7 @@ func (d *MyDialog) runMyDialog(dt ENUM_ENTRY_TYPE) *MyDialog {
 	hBox.Add(widget.NewLabel("    "))
 	buttons := container.NewCenter(hBox)
 	label := container.NewCenter(widget.NewLabel(fmt.Sprintf("Input %s%s", d.value.desc, min)))
-	border := container.NewBorder(label, buttons, nil, nil, entry)
+	border := container.NewBorder(label, buttons, nil, nil, container.NewVBox(entry, d.reason))
 
 	popup := widget.NewModalPopUp(border, d.parent.Canvas())
 	popup.Show()
@@ -216,3 +312,243 @@ func (d *MyDialog) runMyDialog(dt ENUM_ENTRY_TYPE) *MyDialog {
 	popup.Hide()
 	return d
 }
+
+/*
+Select the value from a drop down list of the choices
+*/
+func (d *MyDialog) runChoiceDialog() *MyDialog {
+	ok := widget.NewButtonWithIcon("OK", theme.ConfirmIcon(), nil)
+	ok.Importance = widget.HighImportance
+	sel := widget.NewSelect(d.value.choices, func(s string) {
+		d.onChange(s, ok)
+	})
+	ok.OnTapped = func() {
+		d.commit(sel.Selected)
+	}
+	ca := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		d.abort("input cancelled by user")
+	})
+	d.reason = widget.NewLabelWithStyle("", fyne.TextAlignCenter, fyne.TextStyle{Italic: true})
+	if d.value.IsChoice(d.value.GetValue()) {
+		sel.SetSelected(d.value.GetValue())
+	}
+	d.onChange(sel.Selected, ok)
+
+	buttons := container.NewCenter(container.NewHBox(ca, widget.NewLabel(" "), ok))
+	label := container.NewCenter(widget.NewLabel(fmt.Sprintf("Select %s", d.value.desc)))
+	border := container.NewBorder(label, buttons, nil, nil, container.NewVBox(sel, d.reason))
+
+	popup := widget.NewModalPopUp(border, d.parent.Canvas())
+	popup.Show()
+	d.wait = true
+	for d.wait {
+		time.Sleep(200 * time.Millisecond)
+	}
+	popup.Hide()
+	return d
+}
+
+/*
+Input a bool value with a check box. The value is "true" or "false"
+*/
+func (d *MyDialog) runBoolDialog() *MyDialog {
+	checked, _ := strconv.ParseBool(d.value.GetValue())
+	check := widget.NewCheck(d.value.desc, nil)
+	check.SetChecked(checked)
+	ok := widget.NewButtonWithIcon("OK", theme.ConfirmIcon(), func() {
+		d.commit(strconv.FormatBool(check.Checked))
+	})
+	ok.Importance = widget.HighImportance
+	ca := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		d.abort("input cancelled by user")
+	})
+
+	buttons := container.NewCenter(container.NewHBox(ca, widget.NewLabel(" "), ok))
+	label := container.NewCenter(widget.NewLabel(fmt.Sprintf("Input %s", d.value.desc)))
+	border := container.NewBorder(label, buttons, nil, nil, container.NewCenter(check))
+
+	popup := widget.NewModalPopUp(border, d.parent.Canvas())
+	popup.Show()
+	d.wait = true
+	for d.wait {
+		time.Sleep(200 * time.Millisecond)
+	}
+	popup.Hide()
+	return d
+}
+
+/*
+FormDialog inputs several values in one modal form with one OK and Cancel for all of them
+*/
+type FormDialog struct {
+	debugLog *LogData
+	parent   fyne.Window
+	title    string
+	fields   []*formField
+	wait     bool
+	err      error
+}
+
+type formField struct {
+	fv     *FormValue
+	text   func() string // The current text of the input widget
+	reason *widget.Label // Shows why the value is not valid
+}
+
+func newFormDialog(title string, values []*FormValue, parentWindow fyne.Window, debugLog *LogData) *FormDialog {
+	fields := make([]*formField, 0)
+	for _, fv := range values {
+		fields = append(fields, &formField{fv: fv, reason: widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Italic: true})})
+	}
+	return &FormDialog{title: title, fields: fields, parent: parentWindow, wait: true, err: nil, debugLog: debugLog}
+}
+
+/*
+Returns true if all fields are valid. The reason is shown under each invalid field.
+*/
+func (fd *FormDialog) validate() bool {
+	valid := true
+	for _, f := range fd.fields {
+		err := f.fv.Validate(f.text())
+		if err != nil {
+			f.reason.SetText(err.Error())
+			valid = false
+		} else {
+			f.reason.SetText("")
+		}
+	}
+	return valid
+}
+
+func (fd *FormDialog) onChange(ok *widget.Button) {
+	if fd.validate() {
+		ok.Enable()
+	} else {
+		ok.Disable()
+	}
+}
+
+func (fd *FormDialog) commit() {
+	for _, f := range fd.fields {
+		f.fv.value.SetValue(f.text())
+		f.fv.value.inputDone = true
+		if fd.debugLog.IsLogging() {
+			fd.debugLog.WriteLog(fmt.Sprintf("      Form commit: Value:\"%s\"", f.fv.value))
+		}
+	}
+	fd.wait = false
+}
+
+func (fd *FormDialog) abort(s string) {
+	if fd.debugLog.IsLogging() {
+		fd.debugLog.WriteLog(fmt.Sprintf("      Form abort:\"%s\"", s))
+	}
+	fd.err = fmt.Errorf(s)
+	fd.wait = false
+}
+
+/*
+Create the input widget for the field. onChange is called when the value changes.
+
+The initial value is set before OnChanged so onChange is not called while the form is created.
+It validates every field and the fields that follow do not have their text func yet.
+*/
+func (fd *FormDialog) fieldWidget(f *formField, onChange func()) fyne.CanvasObject {
+	lv := f.fv.value
+	if f.fv.dt == VALUE_DIALOG_TYPE && lv.IsBool() {
+		checked, _ := strconv.ParseBool(lv.GetValue())
+		check := widget.NewCheck("", nil)
+		check.SetChecked(checked)
+		check.OnChanged = func(bool) { onChange() }
+		f.text = func() string { return strconv.FormatBool(check.Checked) }
+		return check
+	}
+	if f.fv.dt == VALUE_DIALOG_TYPE && len(lv.choices) > 0 {
+		sel := widget.NewSelect(lv.choices, nil)
+		if lv.IsChoice(lv.GetValue()) {
+			sel.SetSelected(lv.GetValue())
+		}
+		sel.OnChanged = func(string) { onChange() }
+		f.text = func() string { return sel.Selected }
+		return sel
+	}
+	entry := widget.NewEntry()
+	if lv.isPassword {
+		entry = widget.NewPasswordEntry()
+	}
+	entry.SetText(lv.GetValue())
+	entry.OnChanged = func(string) { onChange() }
+	f.text = func() string { return entry.Text }
+	if !lv.isFileName {
+		return entry
+	}
+	browse := widget.NewButtonWithIcon("", theme.FolderOpenIcon(), func() {
+		fd.browse(f, entry)
+	})
+	return container.NewBorder(nil, nil, nil, browse, entry)
+}
+
+/*
+Choose a file for a file name field. Output files (stdout and stderr) use a save dialog.
+*/
+func (fd *FormDialog) browse(f *formField, entry *widget.Entry) {
+	lv := f.fv.value
+	l, err := lv.GetLastValueAsListableURI()
+	if f.fv.dt == SYSOUT_DIALOG_TYPE {
+		d := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+			if uc != nil {
+				entry.SetText(uc.URI().Path())
+				lv.lastValue = filepath.Dir(uc.URI().Path())
+			}
+		}, fd.parent)
+		if err == nil {
+			d.SetLocation(l)
+		}
+		d.Show()
+		return
+	}
+	d := dialog.NewFileOpen(func(uc fyne.URIReadCloser, err error) {
+		if uc != nil {
+			entry.SetText(uc.URI().Path())
+			lv.lastValue = filepath.Dir(uc.URI().Path())
+		}
+	}, fd.parent)
+	if err == nil {
+		d.SetLocation(l)
+	}
+	d.Show()
+}
+
+func (fd *FormDialog) runFormDialog() error {
+	ok := widget.NewButtonWithIcon("OK", theme.ConfirmIcon(), func() {
+		fd.commit()
+	})
+	ok.Importance = widget.HighImportance
+	ca := widget.NewButtonWithIcon("Cancel", theme.CancelIcon(), func() {
+		fd.abort("input cancelled by user")
+	})
+	onChange := func() {
+		fd.onChange(ok)
+	}
+	form := container.New(layout.NewFormLayout())
+	for _, f := range fd.fields {
+		form.Add(widget.NewLabel(f.fv.value.desc))
+		form.Add(container.NewVBox(fd.fieldWidget(f, onChange), f.reason))
+	}
+	fd.onChange(ok)
+
+	buttons := container.NewCenter(container.NewHBox(ca, widget.NewLabel(" "), ok))
+	label := container.NewCenter(widget.NewLabel(fd.title))
+	border := container.NewBorder(label, buttons, nil, nil, form)
+
+	popup := widget.NewModalPopUp(border, fd.parent.Canvas())
+	size := fd.parent.Canvas().Size()
+	popup.Resize(fyne.NewSize(size.Width*0.7, border.MinSize().Height))
+	popup.Show()
+	fd.wait = true
+	for fd.wait {
+		time.Sleep(200 * time.Millisecond)
+	}
+	popup.Hide()
+	return fd.err
+}
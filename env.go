@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+/*
+Read an env file. Each line is NAME=VALUE. Blank lines and lines starting with # are ignored.
+An 'export ' prefix is allowed and quotes around the value are removed.
+*/
+func ReadEnvFile(fileName string) (map[string]string, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file. %s", err.Error())
+	}
+	defer f.Close()
+	resp := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		name, value, found := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			return nil, fmt.Errorf("env file '%s' line %d. Should be NAME=VALUE", fileName, lineNo)
+		}
+		resp[name] = conditionValue(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file '%s'. %s", fileName, err.Error())
+	}
+	return resp, nil
+}
+
+/*
+Merge the maps. Values in later maps replace values in earlier maps.
+*/
+func MergeEnv(envs ...map[string]string) map[string]string {
+	resp := make(map[string]string)
+	for _, env := range envs {
+		for n, v := range env {
+			resp[n] = v
+		}
+	}
+	return resp
+}
+
+/*
+The environment for the step's command. The step's 'envFile' then 'env' are merged over the
+action's 'env' which is merged over the process environment. Values are substituted.
+
+Returns nil if no env is defined so the command inherits the process environment.
+*/
+func (sa *SingleAction) Environ(actionEnv map[string]string, entryDialog func(*LocalValue) error, dataCache *DataCache) ([]string, error) {
+	if len(actionEnv) == 0 && len(sa.env) == 0 && sa.envFile == "" {
+		return nil, nil
+	}
+	fileEnv := make(map[string]string)
+	if sa.envFile != "" {
+		fn, err := dataCache.Template(sa.envFile, entryDialog)
+		if err != nil {
+			return nil, err
+		}
+		fileEnv, err = ReadEnvFile(fn)
+		if err != nil {
+			return nil, err
+		}
+	}
+	defined := MergeEnv(actionEnv, fileEnv, sa.env)
+	for n, v := range defined {
+		s, err := dataCache.Template(v, entryDialog)
+		if err != nil {
+			return nil, err
+		}
+		defined[n] = s
+	}
+	merged := MergeEnv(dataCache.envMap, defined)
+	resp := make([]string, 0, len(merged))
+	for n, v := range merged {
+		resp = append(resp, fmt.Sprintf("%s=%s", n, v))
+	}
+	sort.Strings(resp)
+	return resp, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestReadEnvFile(t *testing.T) {
+	fileName := "env_test.env"
+	err := os.WriteFile(fileName, []byte("# comment\n\nexport A=\"a val\"\nB = b\nC='c'\n"), 0644)
+	if err != nil {
+		t.Fatalf("[EnvFile:1.0]: Failed to write file %s", err.Error())
+	}
+	defer os.Remove(fileName)
+	env, err := ReadEnvFile(fileName)
+	if err != nil {
+		t.Fatalf("[EnvFile:1.1]: Should not return error %s", err.Error())
+	}
+	testEnvValue(t, env, "A", "a val", "EnvFile:1.2")
+	testEnvValue(t, env, "B", "b", "EnvFile:1.3")
+	testEnvValue(t, env, "C", "c", "EnvFile:1.4")
+
+	os.WriteFile(fileName, []byte("A=a\nnoEquals\n"), 0644)
+	_, err = ReadEnvFile(fileName)
+	if err == nil || !strings.Contains(err.Error(), "line 2. Should be NAME=VALUE") {
+		t.Fatalf("[EnvFile:1.5]: Should return a line 2 error. Got %v", err)
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	dc := NewDataCache()
+	dc.AddLocalValue("who", "Who", "fred", 1, false, false, false, false)
+	sa := NewSingleAction("echo", []string{}, "", "", "", "", "", "", "", "", 0, 0, false)
+	env, err := sa.Environ(map[string]string{}, nil, dc)
+	if err != nil || env != nil {
+		t.Fatalf("[Environ:1.0]: Should return nil. Got %v %v", env, err)
+	}
+	sa.SetEnv(map[string]string{"STEP": "step-%{who}"}, "")
+	env, err = sa.Environ(map[string]string{"STEP": "act", "ACT": "act"}, nil, dc)
+	if err != nil {
+		t.Fatalf("[Environ:1.1]: Should not return error %s", err.Error())
+	}
+	m := make(map[string]string)
+	for _, e := range env {
+		n, v, _ := strings.Cut(e, "=")
+		m[n] = v
+	}
+	testEnvValue(t, m, "STEP", "step-fred", "Environ:1.2")
+	testEnvValue(t, m, "ACT", "act", "Environ:1.3")
+	testEnvValue(t, m, "PATH", os.Getenv("PATH"), "Environ:1.4")
+}
+
+func testEnvValue(t *testing.T, env map[string]string, name, exp, info string) {
+	act, found := env[name]
+	if !found {
+		t.Fatalf("[%s]: '%s' not found", info, name)
+	}
+	if act != exp {
+		t.Fatalf("[%s]: Actual:'%s' != Expected:'%s'", info, act, exp)
+	}
+}
@@ -4,13 +4,27 @@ import (
 	"fmt"
 	"io"
 	"os/exec"
+	"strconv"
 	"time"
 )
 
 const (
-	RC_FAIL  = -2
-	RC_SETUP = -1
-	RC_OK    = 0
+	RC_READ      = -6
+	RC_BUSY      = -5
+	RC_TIMEOUT   = -4
+	RC_CANCELLED = -3
+	RC_FAIL      = -2
+	RC_SETUP     = -1
+	RC_OK        = 0
+
+	TIMEOUT_GRACE_MS       = 2000 // Time between SIGTERM and SIGKILL when a command times out
+	DEFAULT_RETRY_DELAY_MS = 1000 // Time before a failed command is retried
+
+	SYS_RC         = "_rc"        // The return code of the last step run
+	SYS_LAST       = "_last"      // Prefix for the values of the last step run. E.g. _last.durationMs
+	SYS_RC_SUFFIX  = "rc"         // Step return code
+	SYS_DUR_SUFFIX = "durationMs" // Step duration in milliseconds
+	SYS_ST_SUFFIX  = "start"      // Step start time
 )
 
 func execDelayedAction(action *MultipleActionData, delay int, notifyChannel chan *NotifyMessage, dataCache *DataCache) {
@@ -26,7 +40,65 @@ func execDelayedAction(action *MultipleActionData, delay int, notifyChannel chan
 	}()
 }
 
-func execMultipleAction(data *MultipleActionData, notifyChannel chan *NotifyMessage, dataCache *DataCache) {
+/*
+Run an action. Output is written to the terminal unless the action is a 'background' action.
+If the output console exists the output is also written to the console.
+*/
+func execMultipleAction(data *MultipleActionData, notifyChannel chan *NotifyMessage, dataCache *DataCache) int {
+	if data.background && !headlessMode {
+		return execBackgroundAction(data, notifyChannel, dataCache)
+	}
+	if outputConsole != nil {
+		stdOut := NewSysoutWriterTo("", stdColourPrefix[STD_OUT], NewConsoleWriter(outputConsole, data.name, STD_OUT, true))
+		stdErr := NewSysoutWriterTo("", stdColourPrefix[STD_ERR], NewConsoleWriter(outputConsole, data.name, STD_ERR, true))
+		return runMultipleAction(data, stdOut, stdErr, notifyChannel, dataCache)
+	}
+	stdOut := NewSysoutWriter("", stdColourPrefix[STD_OUT])
+	stdErr := NewSysoutWriter("", stdColourPrefix[STD_ERR])
+	return runMultipleAction(data, stdOut, stdErr, notifyChannel, dataCache)
+}
+
+/*
+Run an action with 'background: true'. Output is captured in a BackgroundRun so it can be viewed
+in the 'Background' tab.
+
+A BackgroundAction (BG_ACTION) is not used. It holds the schedule (every, cron) of an action in
+config.schedule. A background action runs when its button is pressed, so it has no schedule.
+A scheduled action that is also a background action is run by the Scheduler and then comes here
+so its runs are recorded as well.
+*/
+func execBackgroundAction(data *MultipleActionData, notifyChannel chan *NotifyMessage, dataCache *DataCache) int {
+	if runningActions.CanStart(data) != nil {
+		//
+		// Let runMultipleAction report why it cannot start. Dont record a run.
+		//
+		return runMultipleAction(data, NewSysoutWriter("", stdColourPrefix[STD_OUT]), NewSysoutWriter("", stdColourPrefix[STD_ERR]), notifyChannel, dataCache)
+	}
+	run := backgroundRuns.Add(data)
+	var stdOut, stdErr *SysoutWriter
+	if outputConsole != nil {
+		stdOut = NewSysoutWriterTo("", "", io.MultiWriter(run.output, NewConsoleWriter(outputConsole, data.name, STD_OUT, false)))
+		stdErr = NewSysoutWriterTo("", "", io.MultiWriter(run.output, NewConsoleWriter(outputConsole, data.name, STD_ERR, false)))
+	} else {
+		stdOut = NewSysoutWriterTo("", "", run.output)
+		stdErr = NewSysoutWriterTo("", "", run.output)
+	}
+	rc := runMultipleAction(data, stdOut, stdErr, notifyChannel, dataCache)
+	run.SetComplete(rc)
+	if notifyChannel != nil {
+		notifyChannel <- NewNotifyMessage(REFRESH, data, "Background action complete", "", rc, nil)
+	}
+	return rc
+}
+
+func runMultipleAction(data *MultipleActionData, stdOut, stdErr *SysoutWriter, notifyChannel chan *NotifyMessage, dataCache *DataCache) int {
+	runner, err := runningActions.Start(data)
+	if err != nil {
+		if notifyChannel != nil {
+			notifyChannel <- NewNotifyMessage(WARN, data, "Action Not Started", "", RC_BUSY, err)
+		}
+		return RC_BUSY
+	}
 	if notifyChannel != nil {
 		notifyChannel <- NewNotifyMessage(START, data, "Action Started", "", RC_OK, nil)
 	}
@@ -35,23 +107,135 @@ func execMultipleAction(data *MultipleActionData, notifyChannel chan *NotifyMess
 			notifyChannel <- NewNotifyMessage(DONE, data, "Action Complete", "", RC_OK, nil)
 		}
 	}()
-	stdOut := NewSysoutWriter("", stdColourPrefix[STD_OUT])
-	stdErr := NewSysoutWriter("", stdColourPrefix[STD_ERR])
-	for i, act := range data.commands {
-		locationMsg := fmt.Sprintf("Action '%s' step '%d' path '%s'", data.desc, i, act.Dir())
-		rc, err := execSingleAction(act, stdOut, stdErr, data.desc, dataCache)
+	defer runningActions.Remove(runner)
+	rc := prepareAction(data, notifyChannel, dataCache)
+	if rc != RC_OK {
+		return rc
+	}
+	rc, _ = runActionSteps(data, data, data.env, stdOut, stdErr, runner, notifyChannel, dataCache)
+	return rc
+}
+
+/*
+Before the first step runs input the values the action needs (see InputValues) then ask
+the user to confirm the action. Returns RC_OK if the action can run.
+*/
+func prepareAction(data *MultipleActionData, notifyChannel chan *NotifyMessage, dataCache *DataCache) int {
+	values := data.InputValues(dataCache)
+	if len(values) > 0 {
+		err := InputValuesDialog(fmt.Sprintf("Input values for action '%s'", data.name), values)
+		if err != nil {
+			if notifyChannel != nil {
+				notifyChannel <- NewNotifyMessage(CANCELLED, data, "Action Cancelled", "Input", RC_CANCELLED, err)
+			}
+			return RC_CANCELLED
+		}
+	}
+	confirmed, err := confirmAction(data, dataCache)
+	if err != nil {
+		if notifyChannel != nil {
+			notifyChannel <- NewNotifyMessage(ERROR, data, "Action Not Started", "Confirm", RC_SETUP, err)
+		}
+		return RC_SETUP
+	}
+	if !confirmed {
+		if notifyChannel != nil {
+			notifyChannel <- NewNotifyMessage(CANCELLED, data, "Action Not Confirmed", "", RC_CANCELLED, nil)
+		}
+		return RC_CANCELLED
+	}
+	return RC_OK
+}
+
+/*
+If the action has a 'confirm' question ask the user before it starts. Returns false if the
+user said No. In headless mode the -yes option is checked before the action is run (see runHeadless).
+*/
+func confirmAction(data *MultipleActionData, dataCache *DataCache) (bool, error) {
+	if data.confirm == "" || headlessMode {
+		return true, nil
+	}
+	question, err := dataCache.Template(data.confirm, ValidatedEntryDialog)
+	if err != nil {
+		return false, err
+	}
+	return ConfirmDialog(fmt.Sprintf("Confirm action '%s'", data.name), question, mainWindow, debugLogMain), nil
+}
+
+/*
+Run the steps (commands) of 'steps'. A step that references another action runs that action's
+steps inline with the same runner and dataCache and inherits 'actionEnv'. Messages are
+reported against 'data', the action that was started.
+
+Returns the rc and true if the action should stop.
+*/
+func runActionSteps(data, steps *MultipleActionData, actionEnv map[string]string, stdOut, stdErr *SysoutWriter, runner *ActionRunner, notifyChannel chan *NotifyMessage, dataCache *DataCache) (int, bool) {
+	for i := 0; i < len(steps.commands); i++ {
+		act := steps.commands[i]
+		locationMsg := fmt.Sprintf("Action '%s' step '%d' path '%s'", steps.desc, i, act.Dir())
+		if runner.IsCancelled() {
+			notifyCancelled(data, locationMsg, len(steps.commands)-i, notifyChannel)
+			return RC_CANCELLED, true
+		}
+		run, reason, err := act.ShouldRun(ValidatedEntryDialog, dataCache)
+		if err != nil {
+			if notifyChannel != nil {
+				notifyChannel <- NewNotifyMessage(ERROR, data, "Error Evaluating Condition", locationMsg, RC_SETUP, err)
+			}
+			return RC_SETUP, true
+		}
+		if !run {
+			if notifyChannel != nil {
+				notifyChannel <- NewNotifyMessage(SKIPPED, data, "Step Skipped", fmt.Sprintf("%s. %s", locationMsg, reason), RC_OK, nil)
+			}
+			continue
+		}
+		if act.action != nil {
+			rc, stop := runActionSteps(data, act.action, MergeEnv(actionEnv, act.action.env), stdOut, stdErr, runner, notifyChannel, dataCache)
+			if stop {
+				return rc, true
+			}
+			continue
+		}
+		var rc int
+		if act.pipe {
+			//
+			// Run this and the following piped commands together. Report the one that failed.
+			//
+			last := i
+			for steps.commands[last].pipe {
+				last++
+			}
+			var failed int
+			rc, err, failed = execPipeline(steps, i, last, actionEnv, stdOut, stdErr, runner, dataCache)
+			act = steps.commands[failed]
+			locationMsg = fmt.Sprintf("Action '%s' step '%d' path '%s'", steps.desc, failed, act.Dir())
+			i = last
+		} else {
+			rc, err = execSingleActionWithRetry(data, act, i, steps, actionEnv, stdOut, stdErr, locationMsg, runner, notifyChannel, dataCache)
+		}
+		if runner.IsCancelled() {
+			notifyCancelled(data, locationMsg, len(steps.commands)-(i+1), notifyChannel)
+			return RC_CANCELLED, true
+		}
 		if err != nil {
 			if rc == RC_SETUP {
 				if notifyChannel != nil {
 					notifyChannel <- NewNotifyMessage(ERROR, data, "Error Setup Process", locationMsg, rc, err)
 				}
-				return
+				return rc, true
 			}
 			if rc == RC_FAIL {
 				if notifyChannel != nil {
 					notifyChannel <- NewNotifyMessage(ERROR, data, "Failed Post Process", locationMsg, rc, err)
 				}
-				return
+				return rc, true
+			}
+			if rc == RC_READ {
+				if notifyChannel != nil {
+					notifyChannel <- NewNotifyMessage(ERROR, data, "Error Reading Input", locationMsg, rc, err)
+				}
+				return rc, true
 			}
 			if act.ignoreError {
 				if notifyChannel != nil {
@@ -62,13 +246,64 @@ func execMultipleAction(data *MultipleActionData, notifyChannel chan *NotifyMess
 				if notifyChannel != nil {
 					notifyChannel <- NewNotifyMessage(CMD_RC, data, exitOsMsg, "", rc, err)
 				}
-				return
+				return rc, true
 			}
 		}
 	}
+	return RC_OK, false
+}
+
+/*
+Run a step. If it fails it is run again up to sa.retries times. Setup errors are not retried.
+Readers and writers are created for each attempt so http input is fetched again and
+http output is posted again.
+*/
+func execSingleActionWithRetry(data *MultipleActionData, sa *SingleAction, step int, steps *MultipleActionData, actionEnv map[string]string, stdOut, stdErr *SysoutWriter, locationMsg string, runner *ActionRunner, notifyChannel chan *NotifyMessage, dataCache *DataCache) (int, error) {
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		rc, err := execSingleAction(sa, actionEnv, nil, stdOut, stdErr, steps.desc, runner, dataCache)
+		setStepSystemValues(steps.name, step, rc, start, time.Since(start), dataCache)
+		if err == nil || rc == RC_SETUP || attempt > sa.retries || runner.IsCancelled() {
+			return rc, err
+		}
+		delay := sa.RetryDelay(attempt)
+		if notifyChannel != nil {
+			notifyChannel <- NewNotifyMessage(RETRY, data, "Retrying Step", fmt.Sprintf("%s. Attempt %d of %d failed. Retry in %d ms", locationMsg, attempt, sa.retries+1, delay), rc, err)
+		}
+		if !runner.Sleep(delay) {
+			return rc, err
+		}
+	}
 }
 
-func execSingleAction(sa *SingleAction, stdOut, stdErr *SysoutWriter, actionDesc string, dataCache *DataCache) (int, error) {
+/*
+Record the result of a step so later steps can use it. Values are written for the last
+step (_rc and _last.*) and for the step by name. E.g. %{actionName.step2.rc}
+*/
+func setStepSystemValues(actionName string, step int, rc int, start time.Time, duration time.Duration, dataCache *DataCache) {
+	stepName := fmt.Sprintf("%s.step%d", actionName, step)
+	values := map[string]string{
+		SYS_RC_SUFFIX:  strconv.Itoa(rc),
+		SYS_DUR_SUFFIX: strconv.FormatInt(duration.Milliseconds(), 10),
+		SYS_ST_SUFFIX:  FormatScheduleTime(start),
+	}
+	dataCache.SetSystemValue(SYS_RC, values[SYS_RC_SUFFIX])
+	for suffix, v := range values {
+		dataCache.SetSystemValue(fmt.Sprintf("%s.%s", SYS_LAST, suffix), v)
+		dataCache.SetSystemValue(fmt.Sprintf("%s.%s", stepName, suffix), v)
+	}
+}
+
+func notifyCancelled(data *MultipleActionData, locationMsg string, skipped int, notifyChannel chan *NotifyMessage) {
+	if notifyChannel != nil {
+		notifyChannel <- NewNotifyMessage(CANCELLED, data, "Action Cancelled", fmt.Sprintf("%s. Skipped %d remaining step(s)", locationMsg, skipped), RC_CANCELLED, nil)
+	}
+}
+
+/*
+Run a single command. 'pipe' is nil unless the command is part of a pipeline (see execPipeline).
+*/
+func execSingleAction(sa *SingleAction, actionEnv map[string]string, pipe *StepPipe, stdOut, stdErr *SysoutWriter, actionDesc string, runner *ActionRunner, dataCache *DataCache) (int, error) {
 	outEncKey, err := derivePasswordFromName(sa.outPwName, sa, dataCache)
 	if err != nil {
 		return RC_SETUP, err
@@ -81,18 +316,35 @@ func execSingleAction(sa *SingleAction, stdOut, stdErr *SysoutWriter, actionDesc
 	if err != nil {
 		return RC_SETUP, err
 	}
-	cmd := exec.Command(sa.command, args...)
+	env, err := sa.Environ(actionEnv, ValidatedEntryDialog, dataCache)
+	if err != nil {
+		return RC_SETUP, err
+	}
+	var cmd *exec.Cmd
+	if sa.shell != "" {
+		script, err := dataCache.ShellTemplate(sa.command, ValidatedEntryDialog)
+		if err != nil {
+			return RC_SETUP, err
+		}
+		cmd = NewShellCommand(sa.shell, script, args)
+	} else {
+		cmd = exec.Command(sa.command, args...)
+	}
+	cmd.Env = env
+	setProcessGroup(cmd)
 	if sa.directory != "" {
 		cmd.Dir = sa.directory
 	}
-	if sa.sysinDef != "" {
+	if pipe != nil && pipe.in != nil {
+		cmd.Stdin = pipe.in
+	} else if sa.sysinDef != "" {
 		tmp, err := substituteValuesIntoString(sa.sysinDef, SysInDialog, dataCache)
 		if err != nil {
 			return RC_SETUP, err
 		}
 		si, err := NewStringReader(tmp, cmd.Stdin, dataCache)
 		if err != nil {
-			return RC_SETUP, err
+			return RC_READ, err
 		}
 		siCloser, ok := si.(io.ReadCloser)
 		if ok {
@@ -108,7 +360,21 @@ func execSingleAction(sa *SingleAction, stdOut, stdErr *SysoutWriter, actionDesc
 	if err != nil {
 		return RC_SETUP, err
 	}
-	so := NewWriter(sysoutDef, outEncKey, stdOut, stdErr, dataCache)
+	var so io.Writer
+	if pipe != nil && pipe.out != nil {
+		_, filter := splitNameFilter(sysoutDef)
+		if filter == "" {
+			so = pipe.out
+			pipe.outDirect = true
+		} else {
+			so, err = NewPipeWriter(pipe.out, filter)
+			if err != nil {
+				return RC_SETUP, err
+			}
+		}
+	} else {
+		so = NewWriter(sysoutDef, outEncKey, stdOut, stdErr, dataCache)
+	}
 	soReset, reSoOk := so.(Reset)
 	if reSoOk {
 		soReset.Reset()
@@ -137,13 +403,29 @@ func execSingleAction(sa *SingleAction, stdOut, stdErr *SysoutWriter, actionDesc
 	// Ready to exec the commands
 	//
 	err = cmd.Start()
+	if pipe != nil {
+		pipe.closeAfterStart()
+	}
 	if err != nil {
 		return cmd.ProcessState.ExitCode(), err
 	}
-	err = cmd.Wait()
+	runner.addCmd(cmd)
+	timedOut, err := waitForCommand(cmd, sa.timeout)
+	runner.removeCmd(cmd)
+	//
+	// Filters that need all of the output (E.g. json:) are applied now. Also if the command
+	// failed or timed out so its output (E.g. "|tail:20") is not lost.
+	//
+	completeErr := completeWriters(so, se)
+	if timedOut {
+		return RC_TIMEOUT, fmt.Errorf("command '%s' timed out after %d ms", sa.command, sa.timeout)
+	}
 	if err != nil {
 		return cmd.ProcessState.ExitCode(), err
 	}
+	if completeErr != nil {
+		return RC_FAIL, completeErr
+	}
 	//
 	// All writers and readers are complete!
 	//
@@ -166,7 +448,9 @@ func execSingleAction(sa *SingleAction, stdOut, stdErr *SysoutWriter, actionDesc
 			if notifyChannel != nil {
 				notifyChannel <- NewNotifyMessage(TO_CLIP, nil, fmt.Sprintf("Copied to Clipboard cmd:%s", sa.String()), "", 0, nil)
 			}
-			mainWindow.Clipboard().SetContent(cp.GetContent())
+			if mainWindow != nil {
+				mainWindow.Clipboard().SetContent(cp.GetContent())
+			}
 		}
 	}
 
@@ -193,6 +477,52 @@ func execSingleAction(sa *SingleAction, stdOut, stdErr *SysoutWriter, actionDesc
 	return RC_OK, nil
 }
 
+/*
+Write the output held by writers with a filter that needs all of it. Returns the first error.
+*/
+func completeWriters(writers ...io.Writer) error {
+	var err error
+	for _, w := range writers {
+		wc, ok := w.(Complete)
+		if ok {
+			cErr := wc.Complete()
+			if cErr != nil && err == nil {
+				err = cErr
+			}
+		}
+	}
+	return err
+}
+
+/*
+Wait for the command to complete. If timeout (ms) is exceeded the command is sent SIGTERM
+and then SIGKILL if it is still running after TIMEOUT_GRACE_MS.
+
+Returns true if the command timed out.
+*/
+func waitForCommand(cmd *exec.Cmd, timeout int) (bool, error) {
+	if timeout <= 0 {
+		return false, cmd.Wait()
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return false, err
+	case <-time.After(time.Duration(timeout) * time.Millisecond):
+	}
+	terminateProcessGroup(cmd)
+	select {
+	case err := <-done:
+		return true, err
+	case <-time.After(TIMEOUT_GRACE_MS * time.Millisecond):
+	}
+	killProcessGroup(cmd)
+	return true, <-done
+}
+
 func substituteValuesIntoArgs(s []string, entryDialog func(*LocalValue) error, dataCache *DataCache) ([]string, error) {
 	resp := make([]string, 0)
 	for _, v := range s {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExecCompleteOnFail(t *testing.T) {
+	dc := NewDataCache()
+	a := NewActionData("A", "", "desc", "", -1, false, false)
+	var out, errOut bytes.Buffer
+	stdOut := NewSysoutWriterTo("", "", &out)
+	stdErr := NewSysoutWriterTo("", "", &errOut)
+
+	sa := a.AddSingleAction("sh", []string{"-c", "printf '{\"a\":1}'; printf 'x\\ny\\nlast\\n' >&2; exit 3"}, "", "", "", "", "memory:json|json:$.a", "|tail:1", "", "", 0, 0, false)
+	rc, err := execSingleAction(sa, nil, nil, stdOut, stdErr, a.desc, NewActionRunner(a), dc)
+	if rc != 3 || err == nil {
+		t.Fatalf("[Exec:1.0]: Should return rc 3 and an error not rc %d and %v", rc, err)
+	}
+	if errOut.String() != "last\n" {
+		t.Fatalf("[Exec:1.1]: Stderr Actual:'%s' != Expected:'last\\n'", errOut.String())
+	}
+	if dc.GetCacheWriter("json").GetContent() != "1" {
+		t.Fatalf("[Exec:1.2]: Memory Actual:'%s' != Expected:'1'", dc.GetCacheWriter("json").GetContent())
+	}
+
+	sa = a.AddSingleAction("sh", []string{"-c", "printf 'nope'"}, "", "", "", "", "memory:bad|json:$.a", "", "", "", 0, 0, false)
+	rc, err = execSingleAction(sa, nil, nil, stdOut, stdErr, a.desc, NewActionRunner(a), dc)
+	if rc != RC_FAIL || err == nil || !strings.HasPrefix(err.Error(), "filter 'json:$.a' input is not JSON.") {
+		t.Fatalf("[Exec:2.0]: Should return RC_FAIL and a JSON error not rc %d and %v", rc, err)
+	}
+}
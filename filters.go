@@ -3,23 +3,88 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"regexp"
 	"strconv"
 	"strings"
 )
 
+const (
+	FILTER_RE_PREF   = "re:"   // A regular expression selector. E.g. re:/^user=(\w+)/i,$1
+	FILTER_NOT_PREF  = "!"     // An exclusion selector. E.g. !DEBUG
+	FILTER_HEAD_PREF = "head:" // The first N lines of the output. E.g. head:5
+	FILTER_TAIL_PREF = "tail:" // The last N lines of the output. E.g. tail:5
+)
+
+var lineRangeRe = regexp.MustCompile(`^(-?\d+)(-(-?\d+)?)?$`)
+
 type Select struct {
 	line     int
+	lineTo   int  // The last line of a range. A negative line counts back from the last line (-1)
+	byLine   bool // Select by line number (line to lineTo)
+	exclude  bool // Lines containing 'contains' are not selected by any selector
 	contains string
 	delim    string
 	index    int
 	suffix   string
+	re       *regexp.Regexp // Regular expression selector. nil for line number and contains selectors
+	template string         // Output for a regular expression match. E.g. $1 or ${name}. Empty for the whole line
+}
+
+/*
+Split a filter in to selectors at each '|'. A '|' in a regular expression (re:/a|b/) does not split it.
+*/
+func splitSelectors(filter string) []string {
+	res := make([]string, 0)
+	start := 0
+	for i := 0; i < len(filter); i++ {
+		if strings.HasPrefix(filter[i:], FILTER_RE_PREF+"/") && (i == start || (i == start+1 && filter[start] == '\'')) {
+			end := regexEnd(filter, i+len(FILTER_RE_PREF))
+			if end > 0 {
+				i = end
+				continue
+			}
+		}
+		if filter[i] == '|' {
+			res = append(res, filter[start:i])
+			start = i + 1
+		}
+	}
+	return append(res, filter[start:])
+}
+
+/*
+The index of the '/' that ends the regular expression that starts with the '/' at 'start'.
+An escaped '\/' does not end it. Returns -1 if there is no end.
+*/
+func regexEnd(s string, start int) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '/':
+			return i
+		}
+	}
+	return -1
 }
 
 func ParseFilter(a string) ([]string, error) {
 	res := make([]string, 0)
 	var sb strings.Builder
 	var inQuote = false
-	for _, c := range a {
+	for i := 0; i < len(a); i++ {
+		c := a[i]
+		if sb.Len() == 0 && !inQuote && strings.HasPrefix(a[i:], FILTER_RE_PREF+"/") {
+			//
+			// Dont split a regular expression at a ','
+			//
+			end := regexEnd(a, i+len(FILTER_RE_PREF))
+			if end > 0 {
+				sb.WriteString(a[i : end+1])
+				i = end
+				continue
+			}
+		}
 		if sb.Len() == 0 && c == '\'' {
 			inQuote = true
 		} else {
@@ -27,14 +92,14 @@ func ParseFilter(a string) ([]string, error) {
 				if c == '\'' {
 					inQuote = false
 				} else {
-					sb.WriteByte(byte(c))
+					sb.WriteByte(c)
 				}
 			} else {
 				if c == ',' {
 					res = append(res, sb.String())
 					sb.Reset()
 				} else {
-					sb.WriteByte(byte(c))
+					sb.WriteByte(c)
 				}
 			}
 		}
@@ -46,8 +111,83 @@ func ParseFilter(a string) ([]string, error) {
 	return res, nil
 }
 
+/*
+A regular expression selector: re:/pattern/flags,template,suffix
+
+Flags are i (ignore case), m (multi line) and s (. matches new line). The template is expanded
+for the first match in the line. $1 or ${1} is a capture group and ${name} is a named group
+(?P<name>...). If the template is empty the whole line is selected.
+*/
+func newRegexSelect(ap []string, a string) (*Select, error) {
+	def := ap[0][len(FILTER_RE_PREF):]
+	end := regexEnd(def, 0)
+	if end < 0 {
+		return nil, fmt.Errorf("regular expression in filter element '%s' must be between '/' characters. E.g. re:/^user=(\\w+)/", a)
+	}
+	pattern := def[1:end]
+	flags := def[end+1:]
+	for _, f := range flags {
+		if !strings.ContainsRune("ims", f) {
+			return nil, fmt.Errorf("regular expression flag '%c' in filter element '%s' is not one of i, m or s", f, a)
+		}
+	}
+	if flags != "" {
+		pattern = fmt.Sprintf("(?%s)%s", flags, pattern)
+	}
+	re, err := regexp.Compile(strings.ReplaceAll(pattern, "\\/", "/"))
+	if err != nil {
+		return nil, fmt.Errorf("regular expression in filter element '%s' is invalid. %s", a, err.Error())
+	}
+	if len(ap) > 3 {
+		return nil, fmt.Errorf("too many parts to filter element '%s'", a)
+	}
+	sel := &Select{index: -1, re: re}
+	if len(ap) > 1 {
+		sel.template = ap[1]
+	}
+	if len(ap) > 2 {
+		sel.suffix = ap[2]
+	}
+	return sel, nil
+}
+
+/*
+A line number (2), a line counted from the end (-1 is the last line), a range (2-10, 1--1)
+or a range to the last line (2-).
+*/
+func parseLineRange(s string) (int, int, bool) {
+	m := lineRangeRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	from, _ := strconv.Atoi(m[1])
+	if m[2] == "" {
+		return from, from, true
+	}
+	if m[3] == "" {
+		return from, -1, true
+	}
+	to, _ := strconv.Atoi(m[3])
+	return from, to, true
+}
+
+/*
+An exclusion selector: !text. Lines containing text are not selected.
+*/
+func newExcludeSelect(ap []string, a string) (*Select, error) {
+	if len(ap) > 1 {
+		return nil, fmt.Errorf("exclusion filter element '%s' cannot have other parts", a)
+	}
+	contains := ap[0][len(FILTER_NOT_PREF):]
+	if contains == "" {
+		return nil, fmt.Errorf("exclusion filter element '%s' must have the text to exclude. E.g. !DEBUG", a)
+	}
+	return &Select{exclude: true, contains: contains, index: -1}, nil
+}
+
 func newSelect(a string, desc string) (*Select, error) {
-	var line int = -1
+	var line, lineTo int
+	var byLine bool
 	var contains string = ""
 	var delim string = ""
 	var ind int = -1
@@ -58,11 +198,23 @@ func newSelect(a string, desc string) (*Select, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parsing failed for filter: '%s'", err.Error())
 	}
-	if len(ap) > 0 {
-		line, err = strconv.Atoi(ap[0])
-		if err != nil {
+	//
+	// A quoted element[0] is always a string selector. E.g. '2022-10' or 'count'
+	//
+	if isQuotedSelector(a) {
+		contains = ap[0]
+	} else {
+		if strings.HasPrefix(ap[0], FILTER_RE_PREF) {
+			return newRegexSelect(ap, a)
+		}
+		if len(ap[0]) > len(FILTER_NOT_PREF) && strings.HasPrefix(ap[0], FILTER_NOT_PREF) {
+			return newExcludeSelect(ap, a)
+		}
+		line, lineTo, byLine = parseLineRange(ap[0])
+		if !byLine {
 			contains = ap[0]
-			line = -1
+		} else if line >= 0 && lineTo >= 0 && line > lineTo {
+			return nil, fmt.Errorf("line range in filter element '%s' ends before it starts. To select lines containing it use '%s'", a, ap[0])
 		}
 	}
 	if len(ap) > 1 && ap[1] != "" {
@@ -80,7 +232,15 @@ func newSelect(a string, desc string) (*Select, error) {
 	if len(ap) > 4 {
 		return nil, fmt.Errorf("too many parts to filter element '%s'", a)
 	}
-	return &Select{line: line, contains: contains, delim: delim, index: ind, suffix: suffix}, nil
+	return &Select{line: line, lineTo: lineTo, byLine: byLine, contains: contains, delim: delim, index: ind, suffix: suffix}, nil
+}
+
+/*
+Returns true if element[0] of the selector is in single quotes so it is a string selector even if
+it looks like a line number, a range, an exclusion, a regular expression or a stage. E.g. 'count'
+*/
+func isQuotedSelector(sel string) bool {
+	return strings.HasPrefix(sel, "'")
 }
 
 func parseSelectArgs(args []string, desc string) ([]*Select, error) {
@@ -95,9 +255,67 @@ func parseSelectArgs(args []string, desc string) ([]*Select, error) {
 	return sels, nil
 }
 
-func selectLineWithArgs(selectList []*Select, ln int, line string, sb *strings.Builder) {
+/*
+Returns true if the selector uses a line counted from the end so the number of lines must be known.
+*/
+func (s *Select) fromEnd() bool {
+	return s.byLine && (s.line < 0 || s.lineTo < 0)
+}
+
+/*
+Returns true if line 'ln' of 'count' lines is in the selected line range.
+*/
+func (s *Select) selectsLine(ln, count int) bool {
+	from, to := s.line, s.lineTo
+	if from < 0 {
+		from = count + from
+	}
+	if to < 0 {
+		to = count + to
+	}
+	return ln >= from && ln <= to
+}
+
+/*
+Write the parts of the line selected by each selector. 'count' is the number of lines in the
+input. It is only used by selectors that count from the last line.
+
+Lines containing the text of an exclusion selector are not selected. If there are only exclusion
+selectors the other lines are output unchanged (with a new line).
+*/
+func selectLineWithArgs(selectList []*Select, ln, count int, line string, sb *strings.Builder) {
+	excludeOnly := true
+	for _, s := range selectList {
+		if s.exclude {
+			if strings.Contains(line, s.contains) {
+				return
+			}
+		} else {
+			excludeOnly = false
+		}
+	}
+	if excludeOnly {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+		return
+	}
 	for _, s := range selectList {
-		if ln == s.line || (s.line == -1 && s.contains != "" && strings.Contains(line, s.contains)) {
+		if s.exclude {
+			continue
+		}
+		if s.re != nil {
+			m := s.re.FindStringSubmatchIndex(line)
+			if m != nil {
+				if s.template == "" {
+					sb.WriteString(line)
+				} else {
+					sb.Write(s.re.ExpandString(nil, s.template, line, m))
+				}
+				sb.WriteString(s.suffix)
+			}
+			continue
+		}
+		if (s.byLine && s.selectsLine(ln, count)) || (!s.byLine && s.contains != "" && strings.Contains(line, s.contains)) {
 			if s.index < 0 || s.delim == "" {
 				sb.WriteString(line)
 				sb.WriteString(s.suffix)
@@ -112,21 +330,317 @@ func selectLineWithArgs(selectList []*Select, ln int, line string, sb *strings.B
 	}
 }
 
-func Filter(input []byte, filter string) ([]byte, error) {
+/*
+LineFilter applies a filter one line at a time. Line numbers continue from one call to the next
+so it can be used on a stream. If a selector counts from the last line (E.g. -1) the filter
+must be applied to all of the output (see Apply).
+*/
+type LineFilter struct {
+	selectList []*Select
+	line       int
+	count      int  // The number of lines if known (see Apply). -1 if not
+	fromEnd    bool // A selector counts from the last line
+}
+
+/*
+An empty filter passes each line through unchanged (with a new line).
+*/
+func NewLineFilter(filter string) (*LineFilter, error) {
 	if filter == "" {
-		return input, nil
+		return &LineFilter{selectList: nil, line: 0, count: -1}, nil
 	}
-	parts := strings.Split(filter, "|")
-	selectList, err := parseSelectArgs(parts, "")
+	selectList, err := parseSelectArgs(splitSelectors(filter), "")
 	if err != nil {
 		return nil, err
 	}
-	var sb strings.Builder
+	fromEnd := false
+	for _, s := range selectList {
+		if s.fromEnd() {
+			fromEnd = true
+		}
+	}
+	return &LineFilter{selectList: selectList, line: 0, count: -1, fromEnd: fromEnd}, nil
+}
+
+func (lf *LineFilter) Line(text string, sb *strings.Builder) {
+	if lf.selectList == nil {
+		sb.WriteString(text)
+		sb.WriteString("\n")
+	} else {
+		selectLineWithArgs(lf.selectList, lf.line, lf.count, text, sb)
+	}
+	lf.line++
+}
+
+/*
+Apply the filter to each line of the input. Line numbers start at 0 for each input so a filter
+can be applied to one input after another. The number of lines is known so lines can be
+counted from the last line.
+*/
+func (lf *LineFilter) Apply(input []byte) ([]byte, error) {
+	lf.line = 0
+	lines := make([]string, 0)
 	scanner := bufio.NewScanner(strings.NewReader(string(input)))
-	line := 0
 	for scanner.Scan() {
-		selectLineWithArgs(selectList, line, scanner.Text(), &sb)
-		line++
+		lines = append(lines, scanner.Text())
+	}
+	lf.count = len(lines)
+	var sb strings.Builder
+	for _, line := range lines {
+		lf.Line(line, &sb)
 	}
 	return []byte(sb.String()), nil
 }
+
+/*
+A filter is a list of stages separated by '|'. Line selectors next to each other are one stage
+that is applied to each line. The other stages (E.g. json:, tail:5 or sort) are applied to all
+of the output of the stage before.
+*/
+type FilterChain struct {
+	stages []*filterStage
+}
+
+type filterStage struct {
+	lines *LineFilter                  // Line selectors. nil if this stage uses all of the output
+	apply func([]byte) ([]byte, error) // Applied to all of the output. nil for line selectors
+}
+
+func NewFilterChain(filter string) (*FilterChain, error) {
+	fc := &FilterChain{stages: make([]*filterStage, 0)}
+	if filter == "" {
+		return fc, nil
+	}
+	lines := make([]string, 0)
+	addLines := func() error {
+		if len(lines) > 0 {
+			lf, err := NewLineFilter(strings.Join(lines, "|"))
+			if err != nil {
+				return err
+			}
+			fc.stages = append(fc.stages, &filterStage{lines: lf})
+			lines = make([]string, 0)
+		}
+		return nil
+	}
+	for _, sel := range splitSelectors(filter) {
+		apply, err := newOutputStage(sel)
+		if err != nil {
+			return nil, err
+		}
+		if apply == nil {
+			lines = append(lines, sel)
+			continue
+		}
+		err = addLines()
+		if err != nil {
+			return nil, err
+		}
+		fc.stages = append(fc.stages, &filterStage{apply: apply})
+	}
+	err := addLines()
+	if err != nil {
+		return nil, err
+	}
+	return fc, nil
+}
+
+/*
+Returns the stage function if the selector is applied to all of the output. nil if it is a line selector.
+*/
+func newOutputStage(sel string) (func([]byte) ([]byte, error), error) {
+	if isQuotedSelector(sel) {
+		return nil, nil
+	}
+	if strings.HasPrefix(sel, FILTER_JSON_PREF) {
+		return newJsonStage(sel[len(FILTER_JSON_PREF):])
+	}
+	if strings.HasPrefix(sel, FILTER_CSV_PREF) || strings.HasPrefix(sel, FILTER_TSV_PREF) {
+		return newCsvStage(sel)
+	}
+	if strings.HasPrefix(sel, FILTER_HEAD_PREF) || strings.HasPrefix(sel, FILTER_TAIL_PREF) {
+		return newHeadTailStage(sel)
+	}
+	return newTransformStage(sel)
+}
+
+/*
+head:N outputs the first N lines and tail:N the last N lines of the output of the stage before.
+*/
+func newHeadTailStage(sel string) (func([]byte) ([]byte, error), error) {
+	head := strings.HasPrefix(sel, FILTER_HEAD_PREF)
+	n, err := strconv.Atoi(sel[strings.IndexByte(sel, ':')+1:])
+	if err != nil || n < 0 {
+		return nil, fmt.Errorf("filter element '%s' must be followed by a number of lines. E.g. %s5", sel, sel[:strings.IndexByte(sel, ':')+1])
+	}
+	return func(input []byte) ([]byte, error) {
+		lines, nl := splitLines(string(input))
+		if n < len(lines) {
+			if head {
+				lines = lines[:n]
+			} else {
+				lines = lines[len(lines)-n:]
+			}
+		}
+		return []byte(joinLines(lines, nl)), nil
+	}, nil
+}
+
+/*
+Returns true if the filter only has line selectors that do not count from the last line so it can
+be applied to output as it is written.
+*/
+func (fc *FilterChain) IsLineFilter() bool {
+	for _, st := range fc.stages {
+		if st.lines == nil || st.lines.fromEnd {
+			return false
+		}
+	}
+	return true
+}
+
+func (fc *FilterChain) Apply(input []byte) ([]byte, error) {
+	var err error
+	for _, st := range fc.stages {
+		if st.lines != nil {
+			input, err = st.lines.Apply(input)
+		} else {
+			input, err = st.apply(input)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return input, nil
+}
+
+/*
+WriterFilter is the filter of a writer. It is parsed once when the writer is created. If the
+filter is invalid the error is returned each time it is applied.
+
+Output is filtered as it is written one complete line at a time. Line numbers continue from one
+write to the next so a range (E.g. 2-) counts the lines of all of the output. If the filter needs
+all of the output (E.g. json:) it is held until Complete is called.
+*/
+type WriterFilter struct {
+	chain *FilterChain // nil if there is no filter or it is invalid
+	err   error        // Why the filter is invalid
+	held  []byte       // Text waiting for a new line (or all of the output if NeedsAllOutput)
+}
+
+func NewWriterFilter(filter string) *WriterFilter {
+	if filter == "" {
+		return &WriterFilter{}
+	}
+	fc, err := NewFilterChain(filter)
+	return &WriterFilter{chain: fc, err: err}
+}
+
+/*
+Returns true if the filter needs all of the output before it can be applied. E.g. json:, sort or -1
+*/
+func (wf *WriterFilter) NeedsAllOutput() bool {
+	return wf != nil && wf.chain != nil && !wf.chain.IsLineFilter()
+}
+
+/*
+Returns the filtered complete lines written so far. Text after the last new line is held until
+the rest of the line is written or Complete is called.
+*/
+func (wf *WriterFilter) Write(p []byte) ([]byte, error) {
+	if wf == nil || (wf.chain == nil && wf.err == nil) {
+		return p, nil
+	}
+	if wf.err != nil {
+		return nil, wf.err
+	}
+	wf.held = append(wf.held, p...)
+	if wf.NeedsAllOutput() {
+		return nil, nil
+	}
+	i := strings.LastIndexByte(string(wf.held), '\n')
+	if i < 0 {
+		return nil, nil
+	}
+	lines := string(wf.held[:i])
+	wf.held = wf.held[i+1:]
+	var sb strings.Builder
+	for _, line := range strings.Split(lines, "\n") {
+		wf.line(strings.TrimRight(line, "\r"), &sb)
+	}
+	return []byte(sb.String()), nil
+}
+
+/*
+Returns the filtered text that is held. Called when the command is complete. The next
+output starts again at line 0.
+*/
+func (wf *WriterFilter) Complete() ([]byte, error) {
+	if wf == nil || len(wf.held) == 0 {
+		wf.Reset()
+		return nil, nil
+	}
+	held := wf.held
+	if wf.NeedsAllOutput() {
+		wf.Reset()
+		return wf.chain.Apply(held)
+	}
+	var sb strings.Builder
+	wf.line(strings.TrimRight(string(held), "\r"), &sb)
+	wf.Reset()
+	return []byte(sb.String()), nil
+}
+
+/*
+Discard any held text and start again at line 0.
+*/
+func (wf *WriterFilter) Reset() {
+	if wf == nil {
+		return
+	}
+	wf.held = nil
+	if wf.chain != nil {
+		for _, st := range wf.chain.stages {
+			if st.lines != nil {
+				st.lines.line = 0
+			}
+		}
+	}
+}
+
+/*
+Filter one line. Line selectors next to each other are one stage so a filter that can be
+applied to each line has at most one stage.
+*/
+func (wf *WriterFilter) line(text string, sb *strings.Builder) {
+	if len(wf.chain.stages) == 0 {
+		sb.WriteString(text)
+		sb.WriteString("\n")
+		return
+	}
+	wf.chain.stages[0].lines.Line(text, sb)
+}
+
+func (wf *WriterFilter) Apply(input []byte) ([]byte, error) {
+	if wf == nil {
+		return input, nil
+	}
+	if wf.err != nil {
+		return nil, wf.err
+	}
+	if wf.chain == nil {
+		return input, nil
+	}
+	return wf.chain.Apply(input)
+}
+
+func Filter(input []byte, filter string) ([]byte, error) {
+	if filter == "" {
+		return input, nil
+	}
+	fc, err := NewFilterChain(filter)
+	if err != nil {
+		return nil, err
+	}
+	return fc.Apply(input)
+}
@@ -141,3 +141,139 @@ func testParseRes(t *testing.T, fil, exp string, expLen int, info string) {
 		t.Fatalf("[%s]: Actual:[]%s] != Expected:[%s]", info, act, exp)
 	}
 }
+
+func TestFilterRegex(t *testing.T) {
+	testParseRes(t, "re:/a,b/i,$1,x", "re:/a,b/i|$1|x|", 3, "Regex:1.0")
+
+	wr, _ = NewCacheWriter("name|re:/^user=(\\w+)/,$1", MEM_TYPE)
+	testResult(t, wr, td1, "stuart", "Regex:2.0")
+	wr, _ = NewCacheWriter("name|re:/^USER\\.(?P<key>\\w+)=(?P<val>.+)$/i,${key}:${val},\n", MEM_TYPE)
+	testResult(t, wr, td1, "email:sdd@gmail.x\n", "Regex:2.1")
+	wr, _ = NewCacheWriter("name|re:/email|=stu/", MEM_TYPE)
+	testResult(t, wr, td1, "user=stuartuser.email=sdd@gmail.x", "Regex:2.2")
+	wr, _ = NewCacheWriter("name|re:/^origin\\s+(\\S+)\\s+\\(fetch\\)/,${1},\n|0,,,\n", MEM_TYPE)
+	testResult(t, wr, "origin\tgit@host:a/b.git (fetch)\norigin\tgit@host:a/b.git (push)\n", "git@host:a/b.git\norigin\tgit@host:a/b.git (fetch)\n", "Regex:2.3")
+	wr, _ = NewCacheWriter("name|re:/a\\/b/", MEM_TYPE)
+	testResult(t, wr, "x/a/b\nab\n", "x/a/b", "Regex:2.4")
+
+	wr, _ = NewCacheWriter("name|re:/abc", MEM_TYPE)
+	testResult(t, wr, td1, "regular expression in filter element 're:/abc' must be between '/' characters. E.g. re:/^user=(\\w+)/", "Regex:3.0")
+	wr, _ = NewCacheWriter("name|re:/abc/x", MEM_TYPE)
+	testResult(t, wr, td1, "regular expression flag 'x' in filter element 're:/abc/x' is not one of i, m or s", "Regex:3.1")
+	wr, _ = NewCacheWriter("name|re:/a(/", MEM_TYPE)
+	testResult(t, wr, td1, "regular expression in filter element 're:/a(/' is invalid. error parsing regexp: missing closing ): `a(`", "Regex:3.2")
+}
+
+const tdJson = `{"auth":{"token":"abc","expires":3600,"ok":true},"users":[{"name":"a"},{"name":"b","roles":["x","y"]}]}`
+
+func TestFilterJson(t *testing.T) {
+	testCompleteResult(t, "name|json:$.auth.token", []string{tdJson}, "abc", "Json:1.0")
+	testCompleteResult(t, "name|json:$.auth.expires", []string{tdJson}, "3600", "Json:1.1")
+	testCompleteResult(t, "name|json:$.auth.ok", []string{tdJson}, "true", "Json:1.2")
+	testCompleteResult(t, "name|json:$.users[1].name", []string{tdJson[:20], tdJson[20:]}, "b", "Json:1.3")
+	testCompleteResult(t, "name|json:$.users[-1].roles[0]", []string{tdJson}, "x", "Json:1.4")
+	testCompleteResult(t, "name|json:$.users[1].roles", []string{tdJson}, `["x","y"]`, "Json:1.5")
+	testCompleteResult(t, "name|json:$.users[0]", []string{tdJson}, `{"name": "a"}`, "Json:1.6")
+	testCompleteResult(t, "name|json:$.auth.token", []string{"\n  " + tdJson + "\n"}, "abc", "Json:1.7")
+	testCompleteResult(t, "name|{,,,|json:$.auth.token", []string{"HTTP/1.1 200 OK\n", tdJson + "\n"}, "abc", "Json:1.8")
+	testCompleteResult(t, "name|json:$.a", []string{`{"a":9007199254740993}`}, "9007199254740993", "Json:1.9")
+	testCompleteResult(t, "name|json:$.l[1]", []string{`{"l":[1, 12345678901234567890 ]}`}, "12345678901234567890", "Json:1.10")
+	testCompleteResult(t, "name|json:$.a", []string{`{"a":1.50}`}, "1.50", "Json:1.11")
+
+	testCompleteResult(t, "name|json:auth", []string{tdJson}, "filter 'json:auth' path must start with '$'", "Json:2.0")
+	testCompleteResult(t, "name|json:$.auth.x", []string{tdJson}, "filter 'json:$.auth.x'. 'x' not found", "Json:2.1")
+	testCompleteResult(t, "name|json:$.users[2]", []string{tdJson}, "filter 'json:$.users[2]'. Index [2] is out of range", "Json:2.2")
+	testCompleteResult(t, "name|json:$.auth[0]", []string{tdJson}, "filter 'json:$.auth[0]'. '[0]' is not a list", "Json:2.3")
+	testCompleteResult(t, "name|json:$.users[a]", []string{tdJson}, "filter 'json:$.users[a]' path index 'a' is not a number", "Json:2.4")
+	testCompleteResult(t, "name|json:$.auth.token", []string{"not json"}, "filter 'json:$.auth.token' input is not JSON.", "Json:2.5")
+
+	testPipeWriter(t, "json:$.users[1].name", []string{tdJson[:30], tdJson[30:]}, "b", "Json:3.0")
+}
+
+func testCompleteResult(t *testing.T, def string, writes []string, exp, info string) {
+	wr, err := NewCacheWriter(def, MEM_TYPE)
+	if err == nil {
+		for _, s := range writes {
+			_, err = wr.Write([]byte(s))
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = wr.Complete()
+	}
+	if err != nil {
+		act := err.Error()
+		if !strings.HasPrefix(act, exp) {
+			t.Fatalf("[%s]: Actual Error :'%s' != Expected Error:'%s'", info, act, exp)
+		}
+		return
+	}
+	act := wr.GetContent()
+	if act != exp {
+		t.Fatalf("[%s]: Actual:'%s' != Expected:'%s'", info, act, exp)
+	}
+}
+
+func TestFilterWriteChunks(t *testing.T) {
+	var sb strings.Builder
+	sw := NewSysoutWriterTo("1-9,,,\n", "", &sb)
+	for _, s := range []string{"header\n", "a\n", "b", "\n"} {
+		sw.Write([]byte(s))
+	}
+	sw.Complete()
+	if sb.String() != "a\nb\n" {
+		t.Fatalf("[Chunks:1.0]: Actual:'%s' != Expected:'a\nb\n'", sb.String())
+	}
+	//
+	// The next output starts again at line 0
+	//
+	sw.Write([]byte("header\nc\n"))
+	sw.Complete()
+	if sb.String() != "a\nb\nc\n" {
+		t.Fatalf("[Chunks:1.1]: Actual:'%s' != Expected:'a\nb\nc\n'", sb.String())
+	}
+
+	cw, _ := NewCacheWriter("name|1,,,\n", MEM_TYPE)
+	cw.Write([]byte("header\nfirst\nsec"))
+	cw.Reset()
+	cw.Write([]byte("ond\nthird\n"))
+	cw.Complete()
+	if cw.GetContent() != "third\n" {
+		t.Fatalf("[Chunks:1.2]: Actual:'%s' != Expected:'third\n'", cw.GetContent())
+	}
+}
+
+const tdLines = "head\nl1 DEBUG\nl2 ERROR\nl3 DEBUG ERROR\nl4 ERROR\n"
+
+func TestFilterLineRange(t *testing.T) {
+	testParseRes(t, "2-10,,,x", "2-10|||x|", 4, "Range:1.0")
+
+	testCompleteResult(t, "name|-1", []string{tdLines}, "l4 ERROR", "Range:2.0")
+	testCompleteResult(t, "name|-2,,,\n", []string{tdLines[:10], tdLines[10:]}, "l3 DEBUG ERROR\n", "Range:2.1")
+	testCompleteResult(t, "name|1-2,,,\n", []string{tdLines}, "l1 DEBUG\nl2 ERROR\n", "Range:2.2")
+	testCompleteResult(t, "name|1-,,,\n", []string{tdLines}, "l1 DEBUG\nl2 ERROR\nl3 DEBUG ERROR\nl4 ERROR\n", "Range:2.3")
+	testCompleteResult(t, "name|1--2, ,0,;", []string{tdLines}, "l1;l2;l3;", "Range:2.4")
+	testCompleteResult(t, "name|-9", []string{tdLines}, "", "Range:2.5")
+	testCompleteResult(t, "name|4-2", []string{tdLines}, "line range in filter element '4-2' ends before it starts", "Range:2.6")
+	testCompleteResult(t, "name|1-2,,,\n", []string{"head\n", "l1 DEBUG\n", "l2 ERROR\n"}, "l1 DEBUG\nl2 ERROR\n", "Range:2.7")
+	testCompleteResult(t, "name|2", []string{"head\nl1 DE", "BUG\nl2 ER", "ROR\nl3"}, "l2 ERROR", "Range:2.8")
+	testCompleteResult(t, "name|DEBUG", []string{"head\nl1 DE", "BUG\r\nl2 ERROR\nl3 DEBUG"}, "l1 DEBUGl3 DEBUG", "Range:2.9")
+	testCompleteResult(t, "name|1-9", []string{"head\nl1"}, "l1", "Range:2.10")
+
+	testCompleteResult(t, "name|!DEBUG", []string{tdLines}, "head\nl2 ERROR\nl4 ERROR\n", "Exclude:3.0")
+	testCompleteResult(t, "name|!DEBUG|ERROR,,,\n", []string{tdLines}, "l2 ERROR\nl4 ERROR\n", "Exclude:3.1")
+	testCompleteResult(t, "name|!DEBUG|!head", []string{tdLines}, "l2 ERROR\nl4 ERROR\n", "Exclude:3.2")
+	testCompleteResult(t, "name|!DEBUG,,,\n", []string{tdLines}, "exclusion filter element '!DEBUG,,,\n' cannot have other parts", "Exclude:3.3")
+
+	testCompleteResult(t, "name|head:2", []string{tdLines}, "head\nl1 DEBUG\n", "HeadTail:4.0")
+	testCompleteResult(t, "name|tail:2", []string{tdLines}, "l3 DEBUG ERROR\nl4 ERROR\n", "HeadTail:4.1")
+	testCompleteResult(t, "name|ERROR,,,\n|tail:1", []string{tdLines}, "l4 ERROR\n", "HeadTail:4.2")
+	testCompleteResult(t, "name|tail:9", []string{tdLines}, tdLines, "HeadTail:4.3")
+	testCompleteResult(t, "name|tail:3|ERROR,,,\n|head:1", []string{tdLines}, "l2 ERROR\n", "HeadTail:4.4")
+	testCompleteResult(t, "name|head:x", []string{tdLines}, "filter element 'head:x' must be followed by a number of lines. E.g. head:5", "HeadTail:4.5")
+
+	testPipeWriter(t, "!DEBUG|tail:1", []string{tdLines[:12], tdLines[12:]}, "l4 ERROR\n", "Pipe:5.0")
+	testPipeWriter(t, "-1,,,\n", []string{tdLines}, "l4 ERROR\n", "Pipe:5.1")
+}
@@ -0,0 +1,124 @@
+package main
+
+/*
+FormValue is a local value that must be input before an action runs. dt is the type of dialog
+it would have been input with. File names in stdout and stderr are output files.
+*/
+type FormValue struct {
+	value *LocalValue
+	dt    ENUM_ENTRY_TYPE
+}
+
+/*
+The values the action needs the user to input. These are the local values with 'input' set
+that have not been input yet and are referenced by the action's desc, confirm or any of its
+steps (including the steps of the actions it calls).
+
+Values with choices from a memory value are not included as the memory value may be set by
+an earlier step. They are input when they are used.
+*/
+func (data *MultipleActionData) InputValues(dataCache *DataCache) []*FormValue {
+	fc := &formCollector{dataCache: dataCache, found: make(map[string]bool), values: make([]*FormValue, 0)}
+	fc.addTemplate(data.desc, VALUE_DIALOG_TYPE)
+	fc.addTemplate(data.confirm, VALUE_DIALOG_TYPE)
+	fc.addSteps(data)
+	return fc.values
+}
+
+type formCollector struct {
+	dataCache *DataCache
+	found     map[string]bool
+	values    []*FormValue
+}
+
+func (fc *formCollector) addSteps(steps *MultipleActionData) {
+	for _, v := range steps.env {
+		fc.addTemplate(v, VALUE_DIALOG_TYPE)
+	}
+	for _, sa := range steps.commands {
+		fc.addTemplate(sa.ifExp, VALUE_DIALOG_TYPE)
+		fc.addTemplate(sa.unlessExp, VALUE_DIALOG_TYPE)
+		if sa.action != nil {
+			fc.addSteps(sa.action)
+			continue
+		}
+		fc.addTemplate(sa.command, VALUE_DIALOG_TYPE)
+		for _, a := range sa.args {
+			fc.addTemplate(a, VALUE_DIALOG_TYPE)
+		}
+		fc.addTemplate(sa.envFile, VALUE_DIALOG_TYPE)
+		for _, v := range sa.env {
+			fc.addTemplate(v, VALUE_DIALOG_TYPE)
+		}
+		fc.addTemplate(sa.sysinDef, SYSIN_DIALOG_TYPE)
+		fc.addTemplate(sa.sysoutDef, SYSOUT_DIALOG_TYPE)
+		fc.addTemplate(sa.syserrDef, SYSOUT_DIALOG_TYPE)
+		fc.addName(sa.inPwName, VALUE_DIALOG_TYPE)
+		fc.addName(sa.outPwName, VALUE_DIALOG_TYPE)
+	}
+}
+
+func (fc *formCollector) addTemplate(s string, dt ENUM_ENTRY_TYPE) {
+	if s == "" {
+		return
+	}
+	TemplateParse(s, func(name string) (string, error) {
+		fc.addName(name, dt)
+		return "", nil
+	})
+}
+
+func (fc *formCollector) addName(name string, dt ENUM_ENTRY_TYPE) {
+	if name == "" || fc.found[name] {
+		return
+	}
+	//
+	// Memory values are substituted before local values
+	//
+	if fc.dataCache.GetCacheWriter(name) != nil {
+		return
+	}
+	lv, ok := fc.dataCache.GetLocalValue(name)
+	if !ok || !lv.inputRequired || lv.inputDone || lv.choicesDef != "" {
+		return
+	}
+	fc.found[name] = true
+	fc.values = append(fc.values, &FormValue{value: lv, dt: dt})
+}
+
+/*
+Input the values. A single value (or any value in headless mode) is input with its own dialog.
+Otherwise all values are input in one form.
+*/
+func InputValuesDialog(title string, values []*FormValue) error {
+	if headlessMode || len(values) == 1 {
+		for _, fv := range values {
+			err := fv.dialogFunc()(fv.value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return newFormDialog(title, values, mainWindow, debugLogMain).runFormDialog()
+}
+
+func (fv *FormValue) dialogFunc() func(*LocalValue) error {
+	switch fv.dt {
+	case SYSIN_DIALOG_TYPE:
+		return SysInDialog
+	case SYSOUT_DIALOG_TYPE:
+		return SysOutDialog
+	}
+	return ValidatedEntryDialog
+}
+
+/*
+The same validation as the dialog the value would have been input with
+*/
+func (fv *FormValue) Validate(s string) error {
+	if fv.dt != VALUE_DIALOG_TYPE {
+		return nil
+	}
+	return fv.value.Validate(s)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2"
+	fynetest "fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+)
+
+func TestInputValues(t *testing.T) {
+	dc := NewDataCache()
+	dc.AddLocalValue("a", "A", "", 1, false, false, false, true)
+	dc.AddLocalValue("b", "B", "", 1, false, false, false, true)
+	dc.AddLocalValue("pw", "PW", "", 1, true, false, false, true)
+	dc.AddLocalValue("out", "Out", "", 1, false, true, false, true)
+	dc.AddLocalValue("fixed", "Fixed", "x", 1, false, false, false, false)
+	done := dc.AddLocalValue("done", "Done", "x", 1, false, false, false, true)
+	done.inputDone = true
+	mem := dc.AddLocalValue("mem", "Mem", "", 1, false, false, false, true)
+	mem.SetChoices(nil, "memory:branches")
+
+	called := NewActionData("called", "", "", "", -1, false, false)
+	called.AddSingleAction("echo", []string{"%{b}"}, "", "", "", "", "", "", "", "", 0, 0, false)
+
+	data := NewActionData("act", "", "Run %{a}", "", -1, false, false)
+	data.AddSingleAction("echo", []string{"%{a}", "%{fixed}", "%{done}", "%{mem}", "%{unknown}"}, "", "", "pw", "", "%{out}", "", "", "", 0, 0, false)
+	data.AddActionRef("called", "", "")
+	data.commands[1].action = called
+
+	values := data.InputValues(dc)
+	exp := []string{"a", "out", "pw", "b"}
+	if len(values) != len(exp) {
+		t.Fatalf("[InputValues:1.0]: Expected %v. Got %d values", exp, len(values))
+	}
+	for i, n := range exp {
+		if values[i].value.name != n {
+			t.Fatalf("[InputValues:1.%d]: Expected '%s'. Got '%s'", i+1, n, values[i].value.name)
+		}
+	}
+	if values[1].dt != SYSOUT_DIALOG_TYPE || values[0].dt != VALUE_DIALOG_TYPE {
+		t.Fatalf("[InputValues:2.0]: Stdout file should use the SYSOUT dialog type")
+	}
+	if values[1].Validate("") != nil || values[0].Validate("") == nil {
+		t.Fatalf("[InputValues:2.1]: Only value dialog types are validated")
+	}
+
+	cw, _ := NewCacheWriter("a", MEM_TYPE)
+	dc.PutCacheWriter(cw)
+	values = data.InputValues(dc)
+	if len(values) != 3 || values[0].value.name != "out" {
+		t.Fatalf("[InputValues:3.0]: A memory value should replace local value 'a'")
+	}
+}
+
+func TestFormDialogInitialValues(t *testing.T) {
+	fynetest.NewApp()
+	dc := NewDataCache()
+	flag := dc.AddLocalValue("flag", "Flag", "true", 0, false, false, false, true)
+	flag.SetType(VT_BOOL, "", 0)
+	env := dc.AddLocalValue("env", "Env", "test", 1, false, false, false, true)
+	env.SetChoices([]string{"dev", "test", "prod"}, "")
+	name := dc.AddLocalValue("name", "Name", "x", 1, false, false, false, true)
+
+	fd := newFormDialog("Form", []*FormValue{{value: flag, dt: VALUE_DIALOG_TYPE}, {value: env, dt: VALUE_DIALOG_TYPE}, {value: name, dt: VALUE_DIALOG_TYPE}}, nil, nil)
+	changes := 0
+	widgets := make([]fyne.CanvasObject, 0)
+	for _, f := range fd.fields {
+		widgets = append(widgets, fd.fieldWidget(f, func() {
+			changes++
+			fd.validate()
+		}))
+	}
+	if changes != 0 {
+		t.Fatalf("[Form:1.0]: onChange should not be called when the form is created. Called %d times", changes)
+	}
+	for i, exp := range []string{"true", "test", "x"} {
+		if fd.fields[i].text() != exp {
+			t.Fatalf("[Form:1.%d]: Actual:'%s' != Expected:'%s'", i+1, fd.fields[i].text(), exp)
+		}
+	}
+	if !fd.validate() {
+		t.Fatalf("[Form:2.0]: Initial values should be valid")
+	}
+	widgets[0].(*widget.Check).SetChecked(false)
+	widgets[1].(*widget.Select).SetSelected("prod")
+	if changes != 2 || fd.fields[0].text() != "false" || fd.fields[1].text() != "prod" {
+		t.Fatalf("[Form:2.1]: Changes should call onChange. Called %d times. Values '%s' '%s'", changes, fd.fields[0].text(), fd.fields[1].text())
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const TERMINAL_INPUT_TRIES = 3
+
+var terminalReader = bufio.NewReader(os.Stdin)
+
+/*
+Run a single named action without the GUI. Output goes to the terminal via the
+default SysoutWriter and input values are prompted for on the terminal.
+
+The returned message contains the code the application should exit with.
+*/
+func runHeadless(actionName string) *NotifyMessage {
+	action, _, err := model.GetActionDataForName(actionName)
+	if err != nil {
+		return NewNotifyMessage(ERROR, nil, "Headless run failed", "", 1, err)
+	}
+	if action.confirm != "" && !HasArg("-yes") {
+		return NewNotifyMessage(ERROR, action, "Action not confirmed", "", 1, fmt.Errorf("action '%s' asks \"%s\". Use the -yes option to confirm it", action.name, model.dataCache.PreviewTemplate(action.confirm, false)))
+	}
+	headlessMode = true
+	done := make(chan int, 1)
+	go func() {
+		done <- execMultipleAction(action, notifyChannel, model.dataCache)
+	}()
+	//
+	// DONE is always the last message sent for an action so read until we get it.
+	//
+	for {
+		notifyMessage := <-notifyChannel
+		if debugLogMain.IsLogging() {
+			debugLogMain.WriteLog(notifyMessage.String())
+		}
+		switch notifyMessage.state {
+		case CMD_RC, ERROR, WARN, CANCELLED, RETRY:
+			fmt.Fprintf(os.Stderr, "%s%s%s\n", stdColourPrefix[STD_ERR], notifyMessage.String(), RESET)
+		}
+		if notifyMessage.state == DONE && notifyMessage.action == action {
+			break
+		}
+	}
+	rc := <-done
+	if rc != RC_OK {
+		return NewNotifyMessage(EXIT_RC, action, "Action failed", "", headlessExitCode(rc), nil)
+	}
+	if action.rc > 0 {
+		return NewNotifyMessage(EXIT_RC, action, "Action complete", "", action.rc, nil)
+	}
+	return NewNotifyMessage(EXIT, action, "Action complete", "", 0, nil)
+}
+
+/*
+Print a preview of the named action (or all actions if the name is empty). Nothing is run.
+*/
+func runDryRun(actionName string) *NotifyMessage {
+	actions := model.actionList
+	if actionName != "" {
+		action, _, err := model.GetActionDataForName(actionName)
+		if err != nil {
+			return NewNotifyMessage(ERROR, nil, "Dry run failed", "", 1, err)
+		}
+		actions = []*MultipleActionData{action}
+	}
+	for _, a := range actions {
+		fmt.Println(PreviewAction(a, model.dataCache))
+	}
+	return NewNotifyMessage(EXIT, nil, "Dry run complete", "", 0, nil)
+}
+
+/*
+Internal return codes are negative. The OS needs a positive value.
+A timeout returns 124 as the 'timeout' command does.
+*/
+func headlessExitCode(rc int) int {
+	if rc == RC_TIMEOUT {
+		return 124
+	}
+	if rc < 0 {
+		return 1
+	}
+	return rc
+}
+
+/*
+The terminal equivalent of MyDialog. An empty response keeps the current value.
+*/
+func TerminalEntryDialog(localValue *LocalValue, isValid func(string, *LocalValue) error, dt ENUM_ENTRY_TYPE) error {
+	for i := 0; i < TERMINAL_INPUT_TRIES; i++ {
+		fmt.Print(terminalPrompt(localValue, dt))
+		s, err := readTerminalLine(localValue.isPassword)
+		if err != nil {
+			return fmt.Errorf("input '%s' cancelled. %s", localValue.name, err.Error())
+		}
+		if s == "" {
+			s = localValue.GetValue()
+		}
+		err = isValid(s, localValue)
+		if err == nil {
+			localValue.SetValue(s)
+			if debugLogMain.IsLogging() {
+				debugLogMain.WriteLog(fmt.Sprintf("      Terminal commit: Value:\"%s\"", localValue))
+			}
+			localValue.inputDone = true
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "%sInvalid value. %s%s\n", stdColourPrefix[STD_ERR], err.Error(), RESET)
+	}
+	return fmt.Errorf("valid input for '%s' was not provided", localValue.name)
+}
+
+func terminalPrompt(localValue *LocalValue, dt ENUM_ENTRY_TYPE) string {
+	var sb strings.Builder
+	sb.WriteString("Input ")
+	sb.WriteString(localValue.desc)
+	if localValue.isFileName {
+		if dt == SYSOUT_DIALOG_TYPE {
+			sb.WriteString(" (output file)")
+		} else {
+			sb.WriteString(" (input file)")
+		}
+	}
+	if localValue.minLen > 0 {
+		sb.WriteString(fmt.Sprintf(". (minimum %d chars)", localValue.minLen))
+	}
+	if localValue.IsBool() {
+		sb.WriteString(" (true or false)")
+	}
+	if localValue.HasChoices() {
+		sb.WriteString(fmt.Sprintf(". One of: %s", strings.Join(localValue.choices, ", ")))
+	}
+	if !localValue.isPassword && localValue.GetValue() != "" {
+		sb.WriteString(fmt.Sprintf(" [%s]", localValue.GetValue()))
+	}
+	sb.WriteString(": ")
+	return sb.String()
+}
+
+func readTerminalLine(hidden bool) (string, error) {
+	if hidden {
+		setTerminalEcho(false)
+		defer func() {
+			setTerminalEcho(true)
+			fmt.Println()
+		}()
+	}
+	s, err := terminalReader.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && s != "" {
+			return strings.TrimRight(s, "\r\n"), nil
+		}
+		return "", err
+	}
+	return strings.TrimRight(s, "\r\n"), nil
+}
+
+func setTerminalEcho(on bool) {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	cmd.Run()
+}
@@ -16,6 +16,7 @@ func HttpPost(url, mimetype, data string) (int, error) {
 	if err != nil {
 		return 999, err
 	}
+	defer resp.Body.Close()
 	return resp.StatusCode, nil
 }
 
@@ -24,6 +25,7 @@ func HttpGet(url string) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/stuartdd2/JsonParser4go/parser"
+)
+
+const FILTER_JSON_PREF = "json:" // Select a value from JSON output. E.g. json:$.auth.token
+
+/*
+A json: filter stage. The path starts with $ (the whole document) followed by .name for an
+object value and [n] for a list value. E.g. $.users[0].name
+
+A string, number, bool or null is output as it is (strings without quotes). A number is
+output as it was in the input so large integers are not rounded. An object or list is output
+as JSON.
+*/
+func newJsonStage(path string) (func([]byte) ([]byte, error), error) {
+	steps, err := parseJsonPath(path)
+	if err != nil {
+		return nil, err
+	}
+	return func(input []byte) ([]byte, error) {
+		root, err := parser.Parse([]byte(strings.TrimSpace(string(input))))
+		if err != nil {
+			return nil, fmt.Errorf("filter 'json:%s' input is not JSON. %s", path, err.Error())
+		}
+		node, err := findJsonNode(root, steps, path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := node.(*parser.JsonNumber); ok {
+			if raw, err := rawJsonValue([]byte(strings.TrimSpace(string(input))), steps); err == nil {
+				return []byte(raw), nil
+			}
+		}
+		return []byte(jsonNodeValue(node)), nil
+	}, nil
+}
+
+/*
+Split the path in to steps. A name step is the name. A list index step is [n].
+*/
+func parseJsonPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("filter 'json:%s' path must start with '$'", path)
+	}
+	steps := make([]string, 0)
+	p := path[1:]
+	for p != "" {
+		switch p[0] {
+		case '.':
+			end := strings.IndexAny(p[1:], ".[")
+			if end < 0 {
+				end = len(p) - 1
+			}
+			name := p[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("filter 'json:%s' path has an empty name", path)
+			}
+			steps = append(steps, name)
+			p = p[end+1:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("filter 'json:%s' path has '[' without ']'", path)
+			}
+			if _, err := strconv.Atoi(p[1:end]); err != nil {
+				return nil, fmt.Errorf("filter 'json:%s' path index '%s' is not a number", path, p[1:end])
+			}
+			steps = append(steps, p[:end+1])
+			p = p[end+1:]
+		default:
+			return nil, fmt.Errorf("filter 'json:%s' path expected '.' or '[' at '%s'", path, p)
+		}
+	}
+	return steps, nil
+}
+
+func findJsonNode(root parser.NodeI, steps []string, path string) (parser.NodeI, error) {
+	node := root
+	for _, step := range steps {
+		if strings.HasPrefix(step, "[") {
+			list, ok := node.(*parser.JsonList)
+			if !ok {
+				return nil, fmt.Errorf("filter 'json:%s'. '%s' is not a list", path, step)
+			}
+			i, _ := strconv.Atoi(step[1 : len(step)-1])
+			if i < 0 {
+				i = list.Len() + i
+			}
+			if i < 0 || i >= list.Len() {
+				return nil, fmt.Errorf("filter 'json:%s'. Index %s is out of range", path, step)
+			}
+			node = list.GetValues()[i]
+			continue
+		}
+		obj, ok := node.(*parser.JsonObject)
+		if !ok {
+			return nil, fmt.Errorf("filter 'json:%s'. Cannot find '%s' in a value that is not an object", path, step)
+		}
+		node = obj.GetNodeWithName(step)
+		if node == nil {
+			return nil, fmt.Errorf("filter 'json:%s'. '%s' not found", path, step)
+		}
+	}
+	return node, nil
+}
+
+func jsonNodeValue(node parser.NodeI) string {
+	switch n := node.(type) {
+	case *parser.JsonString:
+		return n.GetValue()
+	case *parser.JsonObject, *parser.JsonList:
+		//
+		// Remove the name so the JSON is the value only
+		//
+		return parser.Clone(node, "", true).JsonValue()
+	}
+	return node.String()
+}
+
+/*
+The text of the value at the path as it was in the input. The parser holds numbers as
+float64 so an integer above 2^53 would be changed if it was output from the parsed node.
+*/
+func rawJsonValue(input []byte, steps []string) (string, error) {
+	raw := json.RawMessage(input)
+	for _, step := range steps {
+		if strings.HasPrefix(step, "[") {
+			var list []json.RawMessage
+			if err := json.Unmarshal(raw, &list); err != nil {
+				return "", err
+			}
+			i, _ := strconv.Atoi(step[1 : len(step)-1])
+			if i < 0 {
+				i = len(list) + i
+			}
+			if i < 0 || i >= len(list) {
+				return "", fmt.Errorf("index %s is out of range", step)
+			}
+			raw = list[i]
+			continue
+		}
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return "", err
+		}
+		v, ok := obj[step]
+		if !ok {
+			return "", fmt.Errorf("'%s' not found", step)
+		}
+		raw = v
+	}
+	return strings.TrimSpace(string(raw)), nil
+}
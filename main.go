@@ -27,20 +27,24 @@ const (
 
 	VIEW_ACTIONS ViewState = iota
 	VIEW_DATA
+	VIEW_CONSOLE
 )
 
 var (
 	stdColourPrefix       = []string{GREEN, RED}
 	mainWindow            fyne.Window
 	mainWindowActive      bool = false
+	headlessMode          bool = false
 	selectedTabIndex      int  = -1
 	selectedValueTabIndex int  = -1
 
-	currentView        ViewState = VIEW_ACTIONS
-	model              *Model
-	actionRunning      bool = false
-	actionRunningText  string
-	actionRunningLabel *widget.Label
+	currentView ViewState     = VIEW_ACTIONS
+	consoleView *ConsolePanel // The console shown in the console view. nil in other views
+	viewLock    sync.Mutex    // Protects currentView and consoleView
+	model       *Model
+	scheduler   *Scheduler
+
+	outputConsole *Console
 
 	debugLogMain  *LogData
 	refreshLock   sync.Mutex
@@ -70,6 +74,11 @@ func main() {
 		exitApp(NewNotifyMessage(ERROR, nil, "", "", 1, err))
 	}
 	clearLog := HasArg("-lc")
+	dryRun := HasArg("-dryrun")
+	runActionName, err := GetArg("-run")
+	if err != nil {
+		exitApp(NewNotifyMessage(ERROR, nil, "", "", 1, err))
+	}
 
 	if logFileName == "" {
 		debugLogMain = &LogData{logger: nil, queue: nil, maxLineLen: 0}
@@ -111,10 +120,18 @@ func main() {
 		exitApp(NewNotifyMessage(ERROR, nil, "Model Validate Background Tasks Error", "", 1, err))
 	}
 	model.Log()
+	if dryRun {
+		exitApp(runDryRun(runActionName))
+	}
+	if runActionName != "" {
+		exitApp(runHeadless(runActionName))
+	}
+	outputConsole = NewConsole(MAX_CONSOLE_LINES)
 	go listenNotifyChannel()
 	for _, ras := range model.RunAtStart {
 		execDelayedAction(ras.action, ras.delay, notifyChannel, model.dataCache)
 	}
+	scheduler = StartScheduler(model.Schedule, notifyChannel, model.dataCache)
 	gui()
 }
 
@@ -129,28 +146,31 @@ func listenNotifyChannel() {
 		}
 		if mainWindowActive {
 			switch notifyMessage.state {
-			case DONE:
-				notifyActionRunning(false, notifyMessage.action.name)
+			case DONE, START, CANCELLED:
 				refresh()
-			case START:
-				notifyActionRunning(true, notifyMessage.action.name)
 			case CMD_RC:
-				rc := WarnDialog(fmt.Sprintf("Action '%s' failed:", notifyMessage.action.name), notifyMessage.err.Error(), notifyMessage.message, mainWindow, 99, debugLogMain)
-				notifyActionRunning(false, notifyMessage.action.name)
-				if rc == 1 {
-					exitApp(notifyMessage)
-				}
-				refresh()
+				//
+				// Don't block the notify channel. Other actions may still be running.
+				//
+				go func(nm *NotifyMessage) {
+					rc := WarnDialog(fmt.Sprintf("Action '%s' failed:", nm.action.name), nm.err.Error(), nm.message, mainWindow, 99, debugLogMain)
+					if rc == 1 {
+						exitApp(nm)
+					}
+					refresh()
+				}(notifyMessage)
 			case ERROR:
-				WarnDialog(fmt.Sprintf("Action '%s' failed:", notifyMessage.action.name), notifyMessage.err.Error(), "", mainWindow, 8, debugLogMain)
-				refresh()
+				go func(nm *NotifyMessage) {
+					WarnDialog(fmt.Sprintf("Action '%s' failed:", nm.action.name), nm.err.Error(), "", mainWindow, 8, debugLogMain)
+					refresh()
+				}(notifyMessage)
 			case REFRESH:
 				refresh()
 			case WARN:
 				refresh()
 			case EXIT:
 				exitApp(notifyMessage)
-			case LOG:
+			case LOG, SKIPPED, RETRY:
 			}
 		}
 	}
@@ -186,6 +206,7 @@ func gui() {
 		mainWindow.SetTitle("Current dir:" + wd)
 	}
 	warningAtStart()
+	go refreshConsole()
 	mainWindow.SetFixedSize(true)
 	mainWindowActive = true
 	mainWindow.ShowAndRun()
@@ -203,13 +224,45 @@ func newActionButton(label string, icon fyne.Resource, tapped func(action *Multi
 	return ab
 }
 
+func getCurrentView() ViewState {
+	viewLock.Lock()
+	defer viewLock.Unlock()
+	return currentView
+}
+
+func setCurrentView(view ViewState) {
+	viewLock.Lock()
+	defer viewLock.Unlock()
+	currentView = view
+}
+
+/*
+Returns the console panel if the console view is shown. Otherwise nil.
+*/
+func getConsoleView() *ConsolePanel {
+	viewLock.Lock()
+	defer viewLock.Unlock()
+	if currentView != VIEW_CONSOLE {
+		return nil
+	}
+	return consoleView
+}
+
+func setConsoleView(cp *ConsolePanel) {
+	viewLock.Lock()
+	defer viewLock.Unlock()
+	consoleView = cp
+}
+
 func refresh() {
 	refreshLock.Lock()
 	defer refreshLock.Unlock()
 
 	var c fyne.CanvasObject
-	bb := buttonBar()
-	if currentView == VIEW_ACTIONS {
+	var cp *ConsolePanel
+	view := getCurrentView()
+	bb := buttonBar(view)
+	if view == VIEW_ACTIONS {
 		for _, a := range model.actionList {
 			s, _ := substituteValuesIntoString(a.hideExp, nil, model.dataCache)
 			a.ShouldHide = strings.Contains(s, "%{") || s == "yes"
@@ -230,12 +283,19 @@ func refresh() {
 			c = container.NewBorder(bb, nil, nil, nil, cp)
 		}
 	}
-	if currentView == VIEW_DATA {
+	if view == VIEW_DATA {
 		cw := int(math.Floor(float64(mainWindow.Canvas().Size().Width) / float64(MeasureChar())))
 		tabs := container.NewAppTabs()
 		tabs.Append(container.NewTabItem("Local", container.NewVScroll(centerPanelLocalData(model.dataCache, cw))))
 		tabs.Append(container.NewTabItem("Memory", container.NewVScroll(centerPanelMemoryData(model.dataCache, cw))))
 		tabs.Append(container.NewTabItem("Env", container.NewVScroll(centerPanelEnvData(model.dataCache, cw))))
+		tabs.Append(container.NewTabItem("System", container.NewVScroll(centerPanelSystemData(model.dataCache, cw))))
+		if len(model.Schedule) > 0 {
+			tabs.Append(container.NewTabItem("Schedule", container.NewVScroll(centerPanelScheduleData(model.Schedule, cw))))
+		}
+		if backgroundRuns.Len() > 0 {
+			tabs.Append(container.NewTabItem("Background", container.NewVScroll(centerPanelBackgroundData(backgroundRuns.List(), cw))))
+		}
 		if selectedValueTabIndex >= 0 {
 			tabs.SelectIndex(selectedValueTabIndex)
 		}
@@ -244,7 +304,11 @@ func refresh() {
 		}
 		c = container.NewBorder(bb, nil, nil, nil, tabs)
 	}
-	actionRunningLabel.SetText(actionRunningText)
+	if view == VIEW_CONSOLE {
+		cp = NewConsolePanel(outputConsole)
+		c = container.NewBorder(bb, nil, nil, nil, cp.content)
+	}
+	setConsoleView(cp)
 	mainWindow.SetContent(c)
 }
 
@@ -326,6 +390,73 @@ func centerPanelMemoryData(dataCache *DataCache, cw int) *fyne.Container {
 	return vp
 }
 
+func centerPanelSystemData(dataCache *DataCache, cw int) *fyne.Container {
+	vp := container.NewVBox()
+	vp.Add(widget.NewSeparator())
+
+	sortedNames := dataCache.GetSystemValueNamesSorted()
+	max := 0
+	for _, n := range sortedNames {
+		if len(n) > max {
+			max = len(n)
+		}
+	}
+	for _, n := range sortedNames {
+		sv, found := dataCache.GetSystemValue(n)
+		if found {
+			hp := container.NewHBox()
+			s := PadLeft(n, max) + " = "
+			hp.Add(container.New(NewFixedHLayout(100, 14), NewStringFieldLeft(s+CleanString(sv, cw-(len(s)+1)))))
+			vp.Add(hp)
+		}
+	}
+	return vp
+}
+
+func centerPanelScheduleData(schedule []*BackgroundAction, cw int) *fyne.Container {
+	vp := container.NewVBox()
+	vp.Add(widget.NewSeparator())
+
+	max := 0
+	for _, sch := range schedule {
+		if len(sch.name) > max {
+			max = len(sch.name)
+		}
+	}
+	for _, sch := range schedule {
+		next, last, lastRc, running := sch.GetScheduleState()
+		var s string
+		if running {
+			s = fmt.Sprintf("%s (%s) next:%s last:%s running", PadLeft(sch.name, max), sch.ScheduleDesc(), FormatScheduleTime(next), FormatScheduleTime(last))
+		} else {
+			if last.IsZero() {
+				s = fmt.Sprintf("%s (%s) next:%s last:%s", PadLeft(sch.name, max), sch.ScheduleDesc(), FormatScheduleTime(next), FormatScheduleTime(last))
+			} else {
+				s = fmt.Sprintf("%s (%s) next:%s last:%s rc:%d", PadLeft(sch.name, max), sch.ScheduleDesc(), FormatScheduleTime(next), FormatScheduleTime(last), lastRc)
+			}
+		}
+		hp := container.NewHBox()
+		hp.Add(container.New(NewFixedHLayout(100, 14), NewStringFieldLeft(CleanString(s, cw))))
+		vp.Add(hp)
+	}
+	return vp
+}
+
+func centerPanelBackgroundData(runs []*BackgroundRun, cw int) *fyne.Container {
+	vp := container.NewVBox()
+	vp.Add(widget.NewSeparator())
+	for _, r := range runs {
+		run := r
+		hp := container.NewHBox()
+		hp.Add(widget.NewButtonWithIcon("Output", theme.DocumentIcon(), func() {
+			OutputDialog(fmt.Sprintf("Output: %s", run), run.output.String(), mainWindow)
+		}))
+		hp.Add(NewStringFieldLeft(CleanString(run.String(), cw)))
+		vp.Add(hp)
+	}
+	return vp
+}
+
 func centerPanelLocalData(dataCache *DataCache, cw int) *fyne.Container {
 	vp := container.NewVBox()
 	vp.Add(widget.NewSeparator())
@@ -380,13 +511,19 @@ func centerPanelActions(actionData []*MultipleActionData) *fyne.Container {
 		if !l.ShouldHide {
 			hp := container.NewHBox()
 			btn := newActionButton(l.name, theme.SettingsIcon(), func(action *MultipleActionData) {
-				if !actionRunning {
+				err := runningActions.CanStart(action)
+				if err != nil {
+					go WarnDialog("Action not started", err.Error(), "", mainWindow, 5, debugLogMain)
+				} else {
 					go func() {
 						execMultipleAction(action, notifyChannel, model.dataCache)
 					}()
 				}
 			}, l)
 			hp.Add(btn)
+			hp.Add(newActionButton("", theme.VisibilityIcon(), func(action *MultipleActionData) {
+				OutputDialog(fmt.Sprintf("Preview: %s", action.name), PreviewAction(action, model.dataCache), mainWindow)
+			}, l))
 			lab, err := substituteValuesIntoString(l.desc, nil, model.dataCache)
 			if err != nil {
 				lab = l.desc
@@ -403,7 +540,7 @@ func centerPanelActions(actionData []*MultipleActionData) *fyne.Container {
 	return vp
 }
 
-func buttonBar() *fyne.Container {
+func buttonBar(view ViewState) *fyne.Container {
 	bb := container.NewHBox()
 	bb.Add(widget.NewButtonWithIcon("Close", theme.LogoutIcon(), func() {
 		actionClose(NewNotifyMessage(EXIT, nil, "Exit 0", "", 0, nil))
@@ -414,6 +551,13 @@ func buttonBar() *fyne.Container {
 		}))
 	}
 	bb.Add(widget.NewButtonWithIcon("Reload", theme.MediaReplayIcon(), func() {
+		//
+		// The new model restores persisted values from the state file so save the values input in this session first
+		//
+		err := model.SaveState()
+		if err != nil {
+			go WarnDialog("Save State Failed", err.Error(), "", mainWindow, 20, debugLogMain)
+		}
 		m, err := NewModelFromFile(model.homePath, model.fileName, debugLogMain, true, notifyChannel)
 		if err != nil {
 			//
@@ -435,37 +579,46 @@ func buttonBar() *fyne.Container {
 				for _, ras := range model.RunAtStart {
 					execDelayedAction(ras.action, ras.delay, notifyChannel, model.dataCache)
 				}
+				scheduler.Stop()
+				scheduler = StartScheduler(model.Schedule, notifyChannel, model.dataCache)
 			}
 		}
 	}))
-	if currentView == VIEW_ACTIONS {
+	if view != VIEW_ACTIONS {
+		bb.Add(widget.NewButtonWithIcon("Actions", theme.SettingsIcon(), func() {
+			setCurrentView(VIEW_ACTIONS)
+			if notifyChannel != nil {
+				notifyChannel <- NewNotifyMessage(REFRESH, nil, "View action data", "", 0, nil)
+			}
+		}))
+	}
+	if view != VIEW_DATA {
 		bb.Add(widget.NewButtonWithIcon("Values", theme.ComputerIcon(), func() {
-			currentView = VIEW_DATA
+			setCurrentView(VIEW_DATA)
 			if notifyChannel != nil {
 				notifyChannel <- NewNotifyMessage(REFRESH, nil, "View value data", "", 0, nil)
 			}
 		}))
-	} else {
-		bb.Add(widget.NewButtonWithIcon("Actions", theme.SettingsIcon(), func() {
-			currentView = VIEW_ACTIONS
+	}
+	if view != VIEW_CONSOLE {
+		bb.Add(widget.NewButtonWithIcon("Console", theme.ListIcon(), func() {
+			setCurrentView(VIEW_CONSOLE)
 			if notifyChannel != nil {
-				notifyChannel <- NewNotifyMessage(REFRESH, nil, "View action data", "", 0, nil)
+				notifyChannel <- NewNotifyMessage(REFRESH, nil, "View console", "", 0, nil)
 			}
 		}))
 	}
-	actionRunningLabel = widget.NewLabel("")
-	bb.Add(actionRunningLabel)
-	return bb
-}
-
-func notifyActionRunning(newState bool, name string) {
-	actionRunning = newState
-	if actionRunning {
-		actionRunningText = fmt.Sprintf("Running '%s'", name)
-	} else {
-		actionRunningText = ""
+	//
+	// A status entry and a stop button for each running action
+	//
+	for _, r := range runningActions.List() {
+		runner := r
+		bb.Add(widget.NewLabel(fmt.Sprintf("Running '%s'", runner.action.name)))
+		bb.Add(widget.NewButtonWithIcon("", theme.MediaStopIcon(), func() {
+			runner.Cancel()
+		}))
 	}
-	actionRunningLabel.SetText(actionRunningText)
+	return bb
 }
 
 func actionClose(data *NotifyMessage) {
@@ -479,6 +632,12 @@ func actionClose(data *NotifyMessage) {
 }
 
 func exitApp(data *NotifyMessage) {
+	if model != nil {
+		err := model.SaveState()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s%s%s\n", stdColourPrefix[STD_ERR], err.Error(), RESET)
+		}
+	}
 	if debugLogMain != nil && debugLogMain.IsLogging() {
 		debugLogMain.WriteLog(data.String())
 	} else {
@@ -3,9 +3,12 @@ package main
 import (
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/stuartdd2/JsonParser4go/parser"
 )
@@ -37,6 +40,7 @@ var (
 	runAtEndPrefName         = parser.NewDotPath("config.runAtEnd")
 	localConfigPrefName      = parser.NewDotPath("config.localConfig")
 	cacheInputFieldsPrefName = parser.NewDotPath("config.localValues")
+	schedulePrefName         = parser.NewDotPath("config.schedule")
 
 	FILE_APPEND_PREF = "append:" // Used with FileWriter to indicate an append to the file
 	CLIP_BOARD_PREF  = "clip:"   // Used with CacheWriter to indicate that the cache is written to the clipboard
@@ -58,25 +62,38 @@ type Model struct {
 	AltExitRc     int                   // Show additional butten to exit with RC 1
 	RunAtStart    []*BackgroundAction   // Action to run on load
 	RunAtEnd      []*BackgroundAction   // Action to run on exit
+	Schedule      []*BackgroundAction   // Actions to run at intervals or cron times
 	warning       string                // If the model loads dut with warnings
+	stateFile     string                // Persisted values are saved here. Empty if there is no user config dir
 	notifyChannel chan *NotifyMessage
 }
 
 type MultipleActionData struct {
-	tab        string          // The name of the tab it will be listed under
-	name       string          // The name of the action(s)
-	desc       string          // The description of the action(s)
-	hideExp    string          // We ony show the action if the expression does NOT contain %{
-	ShouldHide bool            // Dont show at all, ever.
-	rc         int             // If non ZERO exit the apprication with this error code when action complete
-	commands   []*SingleAction // The list of actions (commands) to execute for this action
+	tab        string            // The name of the tab it will be listed under
+	name       string            // The name of the action(s)
+	desc       string            // The description of the action(s)
+	hideExp    string            // We ony show the action if the expression does NOT contain %{
+	ShouldHide bool              // Dont show at all, ever.
+	rc         int               // If non ZERO exit the apprication with this error code when action complete
+	exclusive  bool              // Only run when no other action is running. Nothing else can run until it is complete
+	background bool              // Capture the output. View it in the Background tab
+	env        map[string]string // Environment variables for every command in the action
+	confirm    string            // If defined ask the user to confirm (Yes/No) before the action starts. Values are substituted
+	commands   []*SingleAction   // The list of actions (commands) to execute for this action
 }
 
 type BackgroundAction struct {
-	bgType BG_ACTION_TYPE
-	name   string
-	delay  int
-	action *MultipleActionData
+	bgType  BG_ACTION_TYPE
+	name    string
+	delay   int
+	action  *MultipleActionData
+	every   int           // BG_ACTION: Run every n milliseconds
+	cron    *CronSchedule // BG_ACTION: Run at the cron times
+	mu      sync.Mutex    // BG_ACTION: Protects the run state below
+	nextRun time.Time     // BG_ACTION: Zero if it will never run
+	lastRun time.Time     // BG_ACTION: Zero if it has not run yet
+	lastRc  int           // BG_ACTION: Return code from the last run
+	running bool          // BG_ACTION: Don't start again until the last run is complete
 }
 
 func (bga *BackgroundAction) String() string {
@@ -86,28 +103,53 @@ func (bga *BackgroundAction) String() string {
 	case BG_ACTION_AT_END:
 		return fmt.Sprintf("RunAtEnd: name:\"%s\"", bga.name)
 	default:
-		return fmt.Sprintf("Background: name:\"%s\"", bga.name)
+		return fmt.Sprintf("Background: name:\"%s\" %s", bga.name, bga.ScheduleDesc())
 	}
 }
 
+func (bga *BackgroundAction) ScheduleDesc() string {
+	if bga.cron != nil {
+		return fmt.Sprintf("cron:\"%s\"", bga.cron)
+	}
+	return fmt.Sprintf("every:%d milliseconds", bga.every)
+}
+
 func (mad *MultipleActionData) String() string {
-	return fmt.Sprintf("Action: tab:\"%s\" name:\"%s\" desc:\"%s\"", mad.tab, mad.name, mad.desc)
+	return fmt.Sprintf("Action: tab:\"%s\" name:\"%s\" desc:\"%s\" exclusive:%t background:%t", mad.tab, mad.name, mad.desc, mad.exclusive, mad.background)
 }
 
 type SingleAction struct {
-	command     string
-	args        []string
-	directory   string
-	sysinDef    string
-	inPwName    string
-	sysoutDef   string
-	syserrDef   string
-	outPwName   string
-	delay       float64
-	ignoreError bool
+	command      string
+	args         []string
+	directory    string
+	sysinDef     string
+	inPwName     string
+	sysoutDef    string
+	syserrDef    string
+	outPwName    string
+	delay        float64
+	timeout      int
+	ignoreError  bool
+	retries      int                 // Run the command again (up to this many times) if it fails
+	retryDelay   int                 // Milliseconds before the first retry
+	retryBackoff float64             // Multiply the delay by this after each retry
+	env          map[string]string   // Environment variables for the command
+	envFile      string              // A file of NAME=VALUE environment variables for the command
+	shell        string              // Run the command as a script with 'shell -c'. Empty for no shell
+	pipe         bool                // Stream stdout to the stdin of the next command. Both run at the same time
+	ifExp        string              // Only run the step if this is true
+	unlessExp    string              // Don't run the step if this is true
+	actionRef    string              // The name of an action to run instead of a command
+	action       *MultipleActionData // The action named by actionRef. Resolved after all actions are loaded
 }
 
 func (sa *SingleAction) String() string {
+	if sa.actionRef != "" {
+		return fmt.Sprintf("action:\"%s\"", sa.actionRef)
+	}
+	if sa.shell != "" {
+		return fmt.Sprintf("path:\"%s\" shell:\"%s\" cmd:\"%s\" args:\"%s\"", sa.Dir(), sa.shell, sa.command, sa.args)
+	}
 	return fmt.Sprintf("path:\"%s\" cmd:\"%s\" args:\"%s\"", sa.Dir(), sa.command, sa.args)
 }
 
@@ -200,6 +242,11 @@ func NewModelFromFile(home, relFileName string, debugLog *LogData, primaryConfig
 			debugLog.WriteLog(fmt.Sprintf("Adding RunAtEnd \"%s\" from file \"%s\"", rae, absFileName))
 		}
 	}
+	mod.Schedule = make([]*BackgroundAction, 0)
+	err = mod.loadSchedule()
+	if err != nil {
+		return nil, err
+	}
 
 	if primaryConfig {
 		localConfigFile := mod.getStringWithFallback(localConfigPrefName, "")
@@ -226,6 +273,25 @@ func NewModelFromFile(home, relFileName string, debugLog *LogData, primaryConfig
 				}
 			}
 		}
+		//
+		// Actions in the local config can be referenced so resolve after the merge
+		//
+		err = mod.ValidateActionRefs()
+		if err != nil {
+			return nil, err
+		}
+		//
+		// Restore persisted values over the values from both config files
+		//
+		mod.stateFile = StateFileName()
+		err = mod.dataCache.LoadState(mod.stateFile, mod.fileName)
+		if err != nil {
+			mod.warning = err.Error()
+		} else {
+			if debugLog.IsLogging() && mod.stateFile != "" {
+				debugLog.WriteLog(fmt.Sprintf("Persisted values restored from \"%s\"", mod.stateFile))
+			}
+		}
 	}
 	return mod, nil
 }
@@ -284,6 +350,14 @@ func (m *Model) MergeModel(localMod *Model) {
 			}
 		}
 	}
+	for _, sch := range localMod.Schedule {
+		if backgroundTaskNotDuplicate(sch, m.Schedule) {
+			m.Schedule = append(m.Schedule, sch)
+			if m.debugLog.IsLogging() {
+				m.debugLog.WriteLog(fmt.Sprintf("Merging: %s", sch))
+			}
+		}
+	}
 	//
 	// Only override to switch it ON
 	//
@@ -303,7 +377,58 @@ func backgroundTaskNotDuplicate(needle *BackgroundAction, hayStack []*Background
 	return true
 }
 
+/*
+Resolve list entries that reference another action. The action must exist and
+must not (directly or indirectly) reference itself.
+*/
+func (m *Model) ValidateActionRefs() error {
+	for _, a := range m.actionList {
+		for i, sa := range a.commands {
+			if sa.actionRef != "" {
+				ref, _, err := m.GetActionDataForName(sa.actionRef)
+				if err != nil {
+					return fmt.Errorf("action '%s' list[%d] references an unknown action. %s", a.name, i, err.Error())
+				}
+				sa.action = ref
+			}
+		}
+	}
+	for _, a := range m.actionList {
+		err := checkActionRefCycle(a, make([]*MultipleActionData, 0))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkActionRefCycle(a *MultipleActionData, path []*MultipleActionData) error {
+	for i, p := range path {
+		if p == a {
+			names := make([]string, 0)
+			for _, pn := range path[i:] {
+				names = append(names, fmt.Sprintf("'%s'", pn.name))
+			}
+			return fmt.Errorf("action '%s' calls itself. %s -> '%s'", a.name, strings.Join(names, " -> "), a.name)
+		}
+	}
+	path = append(path, a)
+	for _, sa := range a.commands {
+		if sa.action != nil {
+			err := checkActionRefCycle(sa.action, path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 func (m *Model) ValidateBackgroundTasks() error {
+	err := m.ValidateActionRefs()
+	if err != nil {
+		return err
+	}
 	for _, bgas := range m.RunAtStart {
 		rass, _, errs := m.GetActionDataForName(bgas.name)
 		if errs != nil {
@@ -318,6 +443,13 @@ func (m *Model) ValidateBackgroundTasks() error {
 		}
 		bgae.action = rase
 	}
+	for _, bgsc := range m.Schedule {
+		rasc, _, errsc := m.GetActionDataForName(bgsc.name)
+		if errsc != nil {
+			return errsc
+		}
+		bgsc.action = rasc
+	}
 	return nil
 }
 
@@ -362,6 +494,7 @@ func (m *Model) loadInputFields() error {
 		isPassword := m.getBoolWithFallback(cacheInputFieldsPrefName.StringAppend(name).StringAppend("isPassword"), false)
 		isFileName := m.getBoolWithFallback(cacheInputFieldsPrefName.StringAppend(name).StringAppend("isFileName"), false)
 		isFileWatch := m.getBoolWithFallback(cacheInputFieldsPrefName.StringAppend(name).StringAppend("isFileWatch"), false)
+		persist := m.getBoolWithFallback(cacheInputFieldsPrefName.StringAppend(name).StringAppend("persist"), false)
 		isCount := 0
 		if isPassword {
 			isCount++
@@ -375,7 +508,26 @@ func (m *Model) loadInputFields() error {
 		if isCount > 1 {
 			return fmt.Errorf("element '%s.%s.desc'. In the config file '%s'. Con only be 1 of isPassword, isFileName or isFileWatch", cacheInputFieldsPrefName, name, m.fileName)
 		}
+		choices, choicesDef, err := getChoicesNode(v.(parser.NodeC), "choices", fmt.Sprintf("%s.%s", cacheInputFieldsPrefName, name))
+		if err != nil {
+			return fmt.Errorf("element '%s.%s.choices'. In the config file '%s'. %s", cacheInputFieldsPrefName, name, m.fileName, err.Error())
+		}
+		if (len(choices) > 0 || choicesDef != "") && isCount > 0 {
+			return fmt.Errorf("element '%s.%s.choices'. In the config file '%s'. Cannot be used with isPassword, isFileName or isFileWatch", cacheInputFieldsPrefName, name, m.fileName)
+		}
 		lv := m.dataCache.AddLocalValue(name, desc, defaultVal, minLen, isPassword, isFileName, isFileWatch, inputRequired)
+		lv.persist = persist
+		lv.SetChoices(choices, choicesDef)
+		err = m.loadValueType(lv, v.(parser.NodeC))
+		if err != nil {
+			return fmt.Errorf("element '%s.%s'. In the config file '%s'. %s", cacheInputFieldsPrefName, name, m.fileName, err.Error())
+		}
+		if defaultVal != "" {
+			err = lv.Validate(defaultVal)
+			if err != nil {
+				return fmt.Errorf("element '%s.%s.value'. In the config file '%s'. %s", cacheInputFieldsPrefName, name, m.fileName, err.Error())
+			}
+		}
 		if m.debugLog.IsLogging() {
 			m.debugLog.WriteLog(fmt.Sprintf("LocalValue loaded name:%s, desc:\"%s\"", lv.name, lv.desc))
 		}
@@ -383,6 +535,100 @@ func (m *Model) loadInputFields() error {
 	return nil
 }
 
+/*
+Read type, pattern, maxLen, min and max. See valuetype.go
+*/
+func (m *Model) loadValueType(lv *LocalValue, node parser.NodeC) error {
+	msg := fmt.Sprintf("%s.%s", cacheInputFieldsPrefName, lv.name)
+	valueType, err := getStringOptNode(node, "type", VT_STRING, msg)
+	if err != nil {
+		return err
+	}
+	pattern, err := getStringOptNode(node, "pattern", "", msg)
+	if err != nil {
+		return err
+	}
+	maxLen, err := getNumberOptNode(node, "maxLen", 0, msg)
+	if err != nil {
+		return err
+	}
+	if isPasswordOrFile := lv.isPassword || lv.isFileName || lv.isFileWatch; isPasswordOrFile && valueType != VT_STRING && valueType != VT_PATH {
+		return fmt.Errorf("type '%s' cannot be used with isPassword, isFileName or isFileWatch", valueType)
+	}
+	err = lv.SetType(valueType, pattern, int(maxLen))
+	if err != nil {
+		return err
+	}
+	var min, max *float64
+	if node.GetNodeWithName("min") != nil {
+		v, err := getNumberOptNode(node, "min", 0, msg)
+		if err != nil {
+			return err
+		}
+		min = &v
+	}
+	if node.GetNodeWithName("max") != nil {
+		v, err := getNumberOptNode(node, "max", 0, msg)
+		if err != nil {
+			return err
+		}
+		max = &v
+	}
+	return lv.SetRange(min, max)
+}
+
+func (m *Model) loadSchedule() error {
+	n, err := parser.Find(m.jsonRoot, schedulePrefName)
+	if err != nil || n == nil {
+		return nil
+	}
+	nl, ok := n.(*parser.JsonList)
+	if !ok {
+		return fmt.Errorf("element '%s'. In the config file '%s'. Is not a List node", schedulePrefName, m.fileName)
+	}
+	for i, v := range nl.GetValues() {
+		msg := fmt.Sprintf("%s[%d]", schedulePrefName, i)
+		if v.GetNodeType() != parser.NT_OBJECT {
+			return fmt.Errorf("element '%s'. In the config file '%s'. Is not an Object node", msg, m.fileName)
+		}
+		s, valid := ValidateNode(SCHEDULE_DEF, v.(parser.NodeC), msg)
+		if !valid {
+			return fmt.Errorf("element '%s'. In the config file '%s'. %s", schedulePrefName, m.fileName, s)
+		}
+		name, err := getStringNode(v.(parser.NodeC), "action", msg)
+		if err != nil {
+			return err
+		}
+		every, err := getNumberOptNode(v.(parser.NodeC), "every", 0, msg)
+		if err != nil {
+			return err
+		}
+		cronSpec, err := getStringOptNode(v.(parser.NodeC), "cron", "", msg)
+		if err != nil {
+			return err
+		}
+		if (every > 0) == (cronSpec != "") {
+			return fmt.Errorf("element '%s'. In the config file '%s'. Must define one of 'every' or 'cron'", msg, m.fileName)
+		}
+		bga := &BackgroundAction{bgType: BG_ACTION, name: name, every: int(every)}
+		if cronSpec != "" {
+			bga.cron, err = ParseCron(cronSpec)
+			if err != nil {
+				return fmt.Errorf("element '%s'. In the config file '%s'. %s", msg, m.fileName, err.Error())
+			}
+		} else {
+			if bga.every < MIN_SCHEDULE_EVERY_MS {
+				return fmt.Errorf("element '%s'. In the config file '%s'. 'every' must be at least %d milliseconds", msg, m.fileName, MIN_SCHEDULE_EVERY_MS)
+			}
+		}
+		m.Schedule = append(m.Schedule, bga)
+		if m.debugLog.IsLogging() {
+			m.debugLog.WriteLog(fmt.Sprintf("Adding %s from file \"%s\"", bga, m.fileName))
+		}
+	}
+	return nil
+}
+
 func (m *Model) Log() {
 	if m.debugLog.IsLogging() {
 		m.debugLog.WriteLog("***** Final State of the Model:")
@@ -393,6 +639,9 @@ func (m *Model) Log() {
 		for _, ras := range m.RunAtEnd {
 			m.debugLog.WriteLog(ras.String())
 		}
+		for _, sch := range m.Schedule {
+			m.debugLog.WriteLog(sch.String())
+		}
 		for _, ad := range m.actionList {
 			m.debugLog.WriteLog(ad.String())
 			for _, sa := range ad.commands {
@@ -459,7 +708,25 @@ func (m *Model) loadActions() error {
 		if err != nil {
 			return err
 		}
-		actionData := m.getActionData(name, tabName, desc, hide, int(exitCode))
+		exclusive, err := getBoolOptNode(actionNode.(parser.NodeC), "exclusive", false, msg)
+		if err != nil {
+			return err
+		}
+		background, err := getBoolOptNode(actionNode.(parser.NodeC), "background", false, msg)
+		if err != nil {
+			return err
+		}
+		env, err := getStringMapNode(actionNode.(parser.NodeC), "env", msg)
+		if err != nil {
+			return err
+		}
+		confirm, err := getStringOptNode(actionNode.(parser.NodeC), "confirm", "", msg)
+		if err != nil {
+			return err
+		}
+		actionData := m.getActionData(name, tabName, desc, hide, int(exitCode), exclusive, background)
+		actionData.env = env
+		actionData.confirm = confirm
 		cmdList, err := getListNode(actionNode.(parser.NodeC), "list")
 		if err != nil {
 			return fmt.Errorf("node at %s does not have a list[] node", msg)
@@ -467,6 +734,26 @@ func (m *Model) loadActions() error {
 
 		for i, cmdNode := range cmdList.GetValues() {
 			msg = fmt.Sprintf("%s -> %s[%d]", msg, "list", i)
+			if cmdNode.GetNodeType() == parser.NT_OBJECT && cmdNode.(parser.NodeC).GetNodeWithName("action") != nil {
+				s, valid := ValidateNode(ACTION_REF_DEF, cmdNode.(parser.NodeC), "Action reference")
+				if !valid {
+					return fmt.Errorf("invalid data for action '%s' list[%d]. %s", name, i, s)
+				}
+				ref, err := getStringNode(cmdNode.(parser.NodeC), "action", msg)
+				if err != nil {
+					return err
+				}
+				ifExp, err := getStringOptNode(cmdNode.(parser.NodeC), "if", "", msg)
+				if err != nil {
+					return err
+				}
+				unlessExp, err := getStringOptNode(cmdNode.(parser.NodeC), "unless", "", msg)
+				if err != nil {
+					return err
+				}
+				actionData.AddActionRef(ref, ifExp, unlessExp)
+				continue
+			}
 			s, valid := ValidateNode(SINGLE_ACTION_DEF, cmdNode.(parser.NodeC), "Command data")
 			if !valid {
 				return fmt.Errorf("invalid data for action '%s' list[%d]. %s", name, i, s)
@@ -525,15 +812,64 @@ func (m *Model) loadActions() error {
 					return fmt.Errorf("for '%s'.' using 'inPwName=%s' without 'sysin' defined", msg, inPwName)
 				}
 			}
+			timeout, err := getNumberOptNode(cmdNode.(parser.NodeC), "timeout", 0, msg)
+			if err != nil {
+				return err
+			}
 			ignoreError, err := getBoolOptNode(cmdNode.(parser.NodeC), "ignoreError", false, msg)
 			if err != nil {
 				return err
 			}
-			actionData.AddSingleAction(cmd, data, path, sysinDef, outPwName, inPwName, sysoutDef, syserrDef, delay, ignoreError)
+			ifExp, err := getStringOptNode(cmdNode.(parser.NodeC), "if", "", msg)
+			if err != nil {
+				return err
+			}
+			unlessExp, err := getStringOptNode(cmdNode.(parser.NodeC), "unless", "", msg)
+			if err != nil {
+				return err
+			}
+			retries, err := getNumberOptNode(cmdNode.(parser.NodeC), "retries", 0, msg)
+			if err != nil {
+				return err
+			}
+			retryDelay, err := getNumberOptNode(cmdNode.(parser.NodeC), "retryDelay", DEFAULT_RETRY_DELAY_MS, msg)
+			if err != nil {
+				return err
+			}
+			retryBackoff, err := getNumberOptNode(cmdNode.(parser.NodeC), "retryBackoff", 1.0, msg)
+			if err != nil {
+				return err
+			}
+			if retries < 0 || retryDelay < 0 || retryBackoff < 1.0 {
+				return fmt.Errorf("for '%s'. 'retries' and 'retryDelay' cannot be negative and 'retryBackoff' must be at least 1", msg)
+			}
+			sa := actionData.AddSingleAction(cmd, data, path, sysinDef, outPwName, inPwName, sysoutDef, syserrDef, ifExp, unlessExp, delay, int(timeout), ignoreError)
+			sa.SetRetry(int(retries), int(retryDelay), retryBackoff)
+			cmdEnv, err := getStringMapNode(cmdNode.(parser.NodeC), "env", msg)
+			if err != nil {
+				return err
+			}
+			envFile, err := getStringOptNode(cmdNode.(parser.NodeC), "envFile", "", msg)
+			if err != nil {
+				return err
+			}
+			sa.SetEnv(cmdEnv, envFile)
+			sa.shell, err = getShellOptNode(cmdNode.(parser.NodeC), "shell", msg)
+			if err != nil {
+				return err
+			}
+			sa.pipe, err = getBoolOptNode(cmdNode.(parser.NodeC), "pipe", false, msg)
+			if err != nil {
+				return err
+			}
 		}
 		if actionData.len() == 0 {
 			return fmt.Errorf("no commands found in 'list' for action '%s' with name '%s'", msg, actionData.name)
 		}
+		err = actionData.validatePipes()
+		if err != nil {
+			return err
+		}
 	}
 	if m.len() == 0 {
 		return fmt.Errorf("node at '%s' did not contain any actions", actionsPrefName)
@@ -630,13 +966,13 @@ func (dc *Model) GetDataCache() *DataCache {
 	return dc.dataCache
 }
 
-func (p *Model) getActionData(name, tabName, desc, hide string, exitCode int) *MultipleActionData {
+func (p *Model) getActionData(name, tabName, desc, hide string, exitCode int, exclusive, background bool) *MultipleActionData {
 	for _, a1 := range p.actionList {
 		if a1.name == name && a1.desc == desc {
 			return a1
 		}
 	}
-	n := NewActionData(name, tabName, desc, hide, exitCode)
+	n := NewActionData(name, tabName, desc, hide, exitCode, exclusive, background)
 	p.actionList = append(p.actionList, n)
 	return n
 }
@@ -711,17 +1047,144 @@ func getStringList(node parser.NodeC, name, msg string) ([]string, error) {
 	return resp, nil
 }
 
-func NewActionData(name, tabName, desc, hide string, exitCode int) *MultipleActionData {
-	return &MultipleActionData{name: name, tab: tabName, desc: desc, rc: exitCode, hideExp: hide, ShouldHide: false, commands: make([]*SingleAction, 0)}
+/*
+The choices node can be a list of strings or a memory value. E.g. "memory:branches|origin/"
+Returns the list or the memory definition.
+*/
+func getChoicesNode(node parser.NodeC, name, msg string) ([]string, string, error) {
+	a := node.GetNodeWithName(name)
+	if a == nil {
+		return nil, "", nil
+	}
+	if a.GetNodeType() == parser.NT_STRING {
+		def := a.(*parser.JsonString).GetValue()
+		if _, _, found := PrefixMatch(def, MEMORY_PREF, MEM_TYPE); !found {
+			return nil, "", fmt.Errorf("node '%s.%s' must be a list or start with '%s'", msg, name, MEMORY_PREF)
+		}
+		return nil, def, nil
+	}
+	resp := make([]string, 0)
+	for _, n := range a.(*parser.JsonList).GetValues() {
+		if n.GetNodeType() != parser.NT_STRING {
+			return nil, "", fmt.Errorf("node '%s.%s' list value '%s' is not a String", msg, name, n.String())
+		}
+		resp = append(resp, n.(*parser.JsonString).GetValue())
+	}
+	if len(resp) == 0 {
+		return nil, "", fmt.Errorf("node '%s.%s' list is empty", msg, name)
+	}
+	return resp, "", nil
 }
 
-func NewSingleAction(cmd string, args []string, directory, sysinDef, outPwName, inPwName, sysoutDef, syserrDef string, delay float64, ignoreError bool) *SingleAction {
-	return &SingleAction{command: cmd, args: args, directory: directory, outPwName: outPwName, inPwName: inPwName, sysinDef: sysinDef, sysoutDef: sysoutDef, syserrDef: syserrDef, delay: delay, ignoreError: ignoreError}
+/*
+The shell node can be the name of the shell or true for DEFAULT_SHELL
+*/
+func getShellOptNode(node parser.NodeC, name, msg string) (string, error) {
+	a := node.GetNodeWithName(name)
+	if a == nil {
+		return "", nil
+	}
+	switch a.GetNodeType() {
+	case parser.NT_BOOL:
+		if a.(*parser.JsonBool).GetValue() {
+			return DEFAULT_SHELL, nil
+		}
+		return "", nil
+	case parser.NT_STRING:
+		return strings.TrimSpace(a.(*parser.JsonString).GetValue()), nil
+	}
+	return "", fmt.Errorf("action node '%s' node '%s' should be a String or a Bool", msg, name)
 }
 
-func (p *MultipleActionData) AddSingleAction(cmd string, args []string, directory, sysinDef, outPwName, inPwName, sysoutDef, syserrDef string, delay float64, ignoreError bool) {
-	sa := NewSingleAction(cmd, args, directory, sysinDef, outPwName, inPwName, sysoutDef, syserrDef, delay, ignoreError)
+func getStringMapNode(node parser.NodeC, name, msg string) (map[string]string, error) {
+	resp := make(map[string]string)
+	a := node.GetNodeWithName(name)
+	if a == nil {
+		return resp, nil
+	}
+	if a.GetNodeType() != parser.NT_OBJECT {
+		return nil, fmt.Errorf("action node '%s' does not contain the 'Object' node '%s'", msg, name)
+	}
+	for _, n := range a.(*parser.JsonObject).GetValues() {
+		if n.GetNodeType() != parser.NT_STRING {
+			return nil, fmt.Errorf("action node '%s.%s' value '%s' is not a String", msg, name, n.GetName())
+		}
+		resp[n.GetName()] = n.(*parser.JsonString).GetValue()
+	}
+	return resp, nil
+}
+
+func NewActionData(name, tabName, desc, hide string, exitCode int, exclusive, background bool) *MultipleActionData {
+	return &MultipleActionData{name: name, tab: tabName, desc: desc, rc: exitCode, hideExp: hide, exclusive: exclusive, background: background, ShouldHide: false, commands: make([]*SingleAction, 0)}
+}
+
+func NewSingleAction(cmd string, args []string, directory, sysinDef, outPwName, inPwName, sysoutDef, syserrDef, ifExp, unlessExp string, delay float64, timeout int, ignoreError bool) *SingleAction {
+	return &SingleAction{command: cmd, args: args, directory: directory, outPwName: outPwName, inPwName: inPwName, sysinDef: sysinDef, sysoutDef: sysoutDef, syserrDef: syserrDef, ifExp: ifExp, unlessExp: unlessExp, delay: delay, timeout: timeout, ignoreError: ignoreError, retries: 0, retryDelay: DEFAULT_RETRY_DELAY_MS, retryBackoff: 1.0}
+}
+
+func (p *MultipleActionData) AddSingleAction(cmd string, args []string, directory, sysinDef, outPwName, inPwName, sysoutDef, syserrDef, ifExp, unlessExp string, delay float64, timeout int, ignoreError bool) *SingleAction {
+	sa := NewSingleAction(cmd, args, directory, sysinDef, outPwName, inPwName, sysoutDef, syserrDef, ifExp, unlessExp, delay, timeout, ignoreError)
 	p.commands = append(p.commands, sa)
+	return sa
+}
+
+func (sa *SingleAction) SetEnv(env map[string]string, envFile string) {
+	sa.env = env
+	sa.envFile = envFile
+}
+
+func (sa *SingleAction) SetRetry(retries, retryDelay int, retryBackoff float64) {
+	sa.retries = retries
+	sa.retryDelay = retryDelay
+	sa.retryBackoff = retryBackoff
+}
+
+/*
+The delay in milliseconds before retry 'n' (starting at 1)
+*/
+func (sa *SingleAction) RetryDelay(n int) int {
+	return int(float64(sa.retryDelay) * math.Pow(sa.retryBackoff, float64(n-1)))
+}
+
+/*
+Add a step that runs the commands of another action
+*/
+func (p *MultipleActionData) AddActionRef(actionName, ifExp, unlessExp string) {
+	p.commands = append(p.commands, &SingleAction{actionRef: actionName, ifExp: ifExp, unlessExp: unlessExp})
+}
+
+/*
+A command with 'pipe' must be followed by a command (not an action) that reads the pipe.
+Only the first command in a pipeline can have a condition and piped commands are not retried.
+*/
+func (p *MultipleActionData) validatePipes() error {
+	for i, sa := range p.commands {
+		if !sa.pipe {
+			continue
+		}
+		msg := fmt.Sprintf("action '%s' list[%d] has 'pipe'", p.name, i)
+		if i == len(p.commands)-1 {
+			return fmt.Errorf("%s but is the last command", msg)
+		}
+		name, _ := splitNameFilter(sa.sysoutDef)
+		if name != "" {
+			return fmt.Errorf("%s so 'stdout' can only be a filter. E.g. '|filter'", msg)
+		}
+		next := p.commands[i+1]
+		if next.actionRef != "" {
+			return fmt.Errorf("%s but the next entry is an action", msg)
+		}
+		if next.sysinDef != "" {
+			return fmt.Errorf("%s so the next command cannot define 'stdin'", msg)
+		}
+		if next.ifExp != "" || next.unlessExp != "" {
+			return fmt.Errorf("%s so the next command cannot have 'if' or 'unless'", msg)
+		}
+		if sa.retries > 0 || next.retries > 0 {
+			return fmt.Errorf("%s. Piped commands cannot have 'retries'", msg)
+		}
+	}
+	return nil
 }
 
 func (p *MultipleActionData) len() int {
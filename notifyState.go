@@ -18,6 +18,9 @@ const (
 	TO_CLIP
 	SAVE_EN
 	LOG
+	CANCELLED
+	SKIPPED
+	RETRY
 )
 
 type NotifyMessage struct {
@@ -89,6 +92,12 @@ func (nm *NotifyMessage) getState() string {
 		return "TO_CLIP:"
 	case SAVE_EN:
 		return "SAVE_EN"
+	case CANCELLED:
+		return "CANCEL: "
+	case SKIPPED:
+		return "SKIPPED:"
+	case RETRY:
+		return "RETRY:  "
 	}
 	return "??????:"
 }
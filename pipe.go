@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+StepPipe connects the stdout of a command to the stdin of the next command in a pipeline.
+*/
+type StepPipe struct {
+	in        *os.File // Read end of the pipe from the previous command. nil for the first command
+	out       *os.File // Write end of the pipe to the next command. nil for the last command
+	outDirect bool     // 'out' was given to the command (no filter) so close it once the command has started
+}
+
+/*
+PipeWriter writes stdout to the next command in a pipeline applying the filter to each
+complete line. If the filter needs all of the output (E.g. json:) it is written when the
+command is complete.
+*/
+type PipeWriter struct {
+	out       *os.File
+	filter    *LineFilter
+	chain     *FilterChain // Not nil if the filter needs all of the output
+	partial   []byte       // Text waiting for a new line (or all of the output if chain is not nil)
+	completed bool         // The remaining text has been written
+}
+
+func NewPipeWriter(out *os.File, filter string) (*PipeWriter, error) {
+	fc, err := NewFilterChain(filter)
+	if err != nil {
+		return nil, err
+	}
+	if !fc.IsLineFilter() {
+		return &PipeWriter{out: out, chain: fc, partial: make([]byte, 0)}, nil
+	}
+	lf, err := NewLineFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	return &PipeWriter{out: out, filter: lf, partial: make([]byte, 0)}, nil
+}
+
+func (pw *PipeWriter) Write(p []byte) (int, error) {
+	pw.partial = append(pw.partial, p...)
+	if pw.chain != nil {
+		return len(p), nil
+	}
+	i := strings.LastIndexByte(string(pw.partial), '\n')
+	if i < 0 {
+		return len(p), nil
+	}
+	lines := string(pw.partial[:i])
+	pw.partial = pw.partial[i+1:]
+	var sb strings.Builder
+	for _, line := range strings.Split(lines, "\n") {
+		pw.filter.Line(strings.TrimRight(line, "\r"), &sb)
+	}
+	if sb.Len() > 0 {
+		_, err := pw.out.WriteString(sb.String())
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+/*
+Filter and write any remaining text. Called when the command is complete.
+*/
+func (pw *PipeWriter) Complete() error {
+	if pw.completed {
+		return nil
+	}
+	pw.completed = true
+	if pw.chain != nil {
+		b, err := pw.chain.Apply(pw.partial)
+		pw.partial = pw.partial[:0]
+		if err != nil {
+			return err
+		}
+		_, err = pw.out.Write(b)
+		return err
+	}
+	if len(pw.partial) > 0 {
+		var sb strings.Builder
+		pw.filter.Line(string(pw.partial), &sb)
+		pw.partial = pw.partial[:0]
+		_, err := pw.out.WriteString(sb.String())
+		return err
+	}
+	return nil
+}
+
+/*
+Write any remaining text then close the pipe so the next command sees the end of its input.
+*/
+func (pw *PipeWriter) Close() error {
+	pw.Complete()
+	return pw.out.Close()
+}
+
+/*
+The command has started so it has its own copy of the pipe ends given to it.
+*/
+func (sp *StepPipe) closeAfterStart() {
+	if sp.in != nil {
+		sp.in.Close()
+	}
+	if sp.out != nil && sp.outDirect {
+		sp.out.Close()
+	}
+}
+
+/*
+Close both ends. Called when the command has completed or failed to start so the commands
+either side of it do not wait for ever.
+*/
+func (sp *StepPipe) Close() {
+	if sp.in != nil {
+		sp.in.Close()
+	}
+	if sp.out != nil {
+		sp.out.Close()
+	}
+}
+
+/*
+Run commands 'from' to 'to' of 'steps' at the same time. The stdout of each command is piped
+to the stdin of the next.
+
+As with a shell, a command that fails because the next command stopped reading its input is
+not an error. Returns the rc and error of the first command that failed and its index.
+*/
+func execPipeline(steps *MultipleActionData, from, to int, actionEnv map[string]string, stdOut, stdErr *SysoutWriter, runner *ActionRunner, dataCache *DataCache) (int, error, int) {
+	n := to - from + 1
+	pipes := make([]*StepPipe, n)
+	for i := range pipes {
+		pipes[i] = &StepPipe{}
+	}
+	for i := 0; i < n-1; i++ {
+		r, w, err := os.Pipe()
+		if err != nil {
+			for _, p := range pipes {
+				p.Close()
+			}
+			return RC_SETUP, fmt.Errorf("failed to create pipe. %s", err.Error()), from + i
+		}
+		pipes[i].out = w
+		pipes[i+1].in = r
+	}
+	rcs := make([]int, n)
+	errs := make([]error, n)
+	starts := make([]time.Time, n)
+	durations := make([]time.Duration, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			starts[i] = time.Now()
+			rcs[i], errs[i] = execSingleAction(steps.commands[from+i], actionEnv, pipes[i], stdOut, stdErr, steps.desc, runner, dataCache)
+			pipes[i].Close()
+			durations[i] = time.Since(starts[i])
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		setStepSystemValues(steps.name, from+i, rcs[i], starts[i], durations[i], dataCache)
+	}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil && !(i < n-1 && isBrokenPipe(errs[i])) {
+			return rcs[i], errs[i], from + i
+		}
+	}
+	return RC_OK, nil, to
+}
+
+func isBrokenPipe(err error) bool {
+	return strings.Contains(err.Error(), "broken pipe")
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestPipeWriterFilter(t *testing.T) {
+	testPipeWriter(t, "b,,,;", []string{"a\nb", "c\nb2\n", "xb3"}, "bc;b2;xb3;", "Pipe:1.0")
+	testPipeWriter(t, "1,,,;|3,,,;", []string{"l0\nl1\nl", "2\nl3\n"}, "l1;l3;", "Pipe:1.1")
+}
+
+func TestPipeWriterNoFilter(t *testing.T) {
+	testPipeWriter(t, "", []string{"a\nb", "c\n", "d"}, "a\nbc\nd\n", "NoFilter:1.0")
+}
+
+func testPipeWriter(t *testing.T, filter string, writes []string, exp, info string) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("[%s]: Failed to create pipe %s", info, err.Error())
+	}
+	defer r.Close()
+	pw, err := NewPipeWriter(w, filter)
+	if err != nil {
+		t.Fatalf("[%s]: Should not return error %s", info, err.Error())
+	}
+	go func() {
+		for _, s := range writes {
+			pw.Write([]byte(s))
+		}
+		pw.Close()
+	}()
+	act, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("[%s]: Read failed %s", info, err.Error())
+	}
+	if string(act) != exp {
+		t.Fatalf("[%s]: Actual:'%s' != Expected:'%s'", info, act, exp)
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const PREVIEW_INDENT = "    "
+
+/*
+Describe what an action would do without running anything. Values are substituted but
+input dialogs are not shown. Values that have not been input yet are shown as <input:name>.
+*/
+func PreviewAction(data *MultipleActionData, dataCache *DataCache) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Action: '%s'", data.name))
+	if data.desc != "" {
+		sb.WriteString(fmt.Sprintf(" (%s)", dataCache.PreviewTemplate(data.desc, false)))
+	}
+	sb.WriteString("\n")
+	if data.exclusive {
+		sb.WriteString("Runs exclusively. No other action can run at the same time\n")
+	}
+	if data.background {
+		sb.WriteString("Runs in the background. Output is captured\n")
+	}
+	if data.confirm != "" {
+		sb.WriteString(fmt.Sprintf("Asks for confirmation: '%s'\n", dataCache.PreviewTemplate(data.confirm, false)))
+	}
+	if data.rc > 0 {
+		sb.WriteString(fmt.Sprintf("Exit gtool with RC=%d when complete (headless only)\n", data.rc))
+	}
+	previewSteps(&sb, data, data.env, "", dataCache)
+	return sb.String()
+}
+
+func previewSteps(sb *strings.Builder, steps *MultipleActionData, actionEnv map[string]string, indent string, dataCache *DataCache) {
+	for i, sa := range steps.commands {
+		sb.WriteString(fmt.Sprintf("%sStep %d:\n", indent, i))
+		in := indent + PREVIEW_INDENT
+		previewCondition(sb, "If", sa.ifExp, true, in, dataCache)
+		previewCondition(sb, "Unless", sa.unlessExp, false, in, dataCache)
+		if sa.action != nil {
+			sb.WriteString(fmt.Sprintf("%sRun action: '%s'\n", in, sa.actionRef))
+			previewSteps(sb, sa.action, MergeEnv(actionEnv, sa.action.env), in, dataCache)
+			continue
+		}
+		if sa.shell != "" {
+			sb.WriteString(fmt.Sprintf("%sShell:     %s -c\n", in, sa.shell))
+			sb.WriteString(fmt.Sprintf("%sScript:    %s\n", in, dataCache.PreviewTemplate(sa.command, true)))
+			if len(sa.args) > 0 {
+				sb.WriteString(fmt.Sprintf("%sArgs:      %s\n", in, previewArgs(sa.args, dataCache)))
+			}
+		} else {
+			sb.WriteString(fmt.Sprintf("%sCommand:   %s\n", in, strings.TrimSpace(sa.command+" "+previewArgs(sa.args, dataCache))))
+		}
+		sb.WriteString(fmt.Sprintf("%sDirectory: %s\n", in, sa.Dir()))
+		if i > 0 && steps.commands[i-1].pipe {
+			sb.WriteString(fmt.Sprintf("%sStdin:     piped from step %d\n", in, i-1))
+		} else {
+			sb.WriteString(fmt.Sprintf("%sStdin:     %s\n", in, previewInput(dataCache.PreviewTemplate(sa.sysinDef, false))))
+		}
+		if sa.pipe {
+			_, filter := splitNameFilter(sa.sysoutDef)
+			sb.WriteString(fmt.Sprintf("%sStdout:    piped to step %d%s\n", in, i+1, previewFilter(filter)))
+		} else {
+			sb.WriteString(fmt.Sprintf("%sStdout:    %s\n", in, previewOutput(dataCache.PreviewTemplate(sa.sysoutDef, false))))
+		}
+		sb.WriteString(fmt.Sprintf("%sStderr:    %s\n", in, previewOutput(dataCache.PreviewTemplate(sa.syserrDef, false))))
+		if sa.inPwName != "" {
+			sb.WriteString(fmt.Sprintf("%sDecrypt:   stdin is decrypted with the password in '%s'\n", in, sa.inPwName))
+		}
+		if sa.outPwName != "" {
+			sb.WriteString(fmt.Sprintf("%sEncrypt:   stdout is encrypted with the password in '%s'\n", in, sa.outPwName))
+		}
+		previewEnv(sb, sa, actionEnv, in, dataCache)
+		if sa.timeout > 0 {
+			sb.WriteString(fmt.Sprintf("%sTimeout:   %d ms\n", in, sa.timeout))
+		}
+		if sa.retries > 0 {
+			sb.WriteString(fmt.Sprintf("%sRetries:   %d. First after %d ms\n", in, sa.retries, sa.RetryDelay(1)))
+		}
+		if sa.ignoreError {
+			sb.WriteString(fmt.Sprintf("%sErrors are ignored\n", in))
+		}
+	}
+}
+
+func previewCondition(sb *strings.Builder, name, expr string, runIf bool, indent string, dataCache *DataCache) {
+	if expr == "" {
+		return
+	}
+	s := dataCache.PreviewTemplate(expr, false)
+	result := "cannot be evaluated until it runs"
+	if !strings.Contains(s, "<input:") && !strings.Contains(s, "<password:") {
+		ok, err := evaluateConditionValue(expr, s)
+		if err != nil {
+			result = err.Error()
+		} else {
+			if ok == runIf {
+				result = "runs now"
+			} else {
+				result = "skipped now"
+			}
+		}
+	}
+	sb.WriteString(fmt.Sprintf("%s%s:%s'%s' -> '%s' %s\n", indent, name, strings.Repeat(" ", 10-len(name)), expr, s, result))
+}
+
+func previewEnv(sb *strings.Builder, sa *SingleAction, actionEnv map[string]string, indent string, dataCache *DataCache) {
+	if sa.envFile != "" {
+		sb.WriteString(fmt.Sprintf("%sEnv file:  %s\n", indent, dataCache.PreviewTemplate(sa.envFile, false)))
+	}
+	env := MergeEnv(actionEnv, sa.env)
+	names := make([]string, 0)
+	for n := range env {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		sb.WriteString(fmt.Sprintf("%sEnv:       %s=%s\n", indent, n, dataCache.PreviewTemplate(env[n], false)))
+	}
+}
+
+func previewArgs(args []string, dataCache *DataCache) string {
+	resp := make([]string, 0)
+	for _, a := range args {
+		s := dataCache.PreviewTemplate(a, false)
+		if s == "" || strings.ContainsAny(s, " \t\n'\"$`\\|&;<>()*?") {
+			s = ShellQuote(s)
+		}
+		resp = append(resp, s)
+	}
+	return strings.Join(resp, " ")
+}
+
+func previewFilter(filter string) string {
+	if filter == "" {
+		return ""
+	}
+	return fmt.Sprintf(". Filter '%s'", filter)
+}
+
+func previewInput(def string) string {
+	if def == "" {
+		return "none"
+	}
+	if fn, _, found := PrefixMatch(def, HTTP_PREF, HTTP_TYPE); found {
+		url, filter := splitNameFilter(fn)
+		return fmt.Sprintf("http GET '%s'%s", url, previewFilter(filter))
+	}
+	if fn, _, found := PrefixMatch(def, MEMORY_PREF, MEM_TYPE); found {
+		name, filter := splitNameFilter(fn)
+		return fmt.Sprintf("memory value '%s'%s", name, previewFilter(filter))
+	}
+	if fn, _, found := PrefixMatch(def, FILE_PREF, FILE_TYPE); found {
+		name, filter := splitNameFilter(fn)
+		return fmt.Sprintf("file '%s'%s", name, previewFilter(filter))
+	}
+	return fmt.Sprintf("the text '%s'", def)
+}
+
+func previewOutput(def string) string {
+	name, filter := splitNameFilter(def)
+	if name == "" {
+		return "terminal" + previewFilter(filter)
+	}
+	if fn, _, found := PrefixMatch(name, HTTP_PREF, HTTP_TYPE); found {
+		return fmt.Sprintf("http POST '%s'%s", fn, previewFilter(filter))
+	}
+	if fn, _, found := PrefixMatch(name, CLIP_BOARD_PREF, CLIP_TYPE); found {
+		return fmt.Sprintf("memory value '%s' and the clipboard%s", fn, previewFilter(filter))
+	}
+	if fn, _, found := PrefixMatch(name, MEMORY_PREF, MEM_TYPE); found {
+		return fmt.Sprintf("memory value '%s'%s", fn, previewFilter(filter))
+	}
+	if fn, _, found := PrefixMatch(name, FILE_APPEND_PREF, FILE_TYPE); found {
+		return fmt.Sprintf("append to file '%s'%s", fn, previewFilter(filter))
+	}
+	return fmt.Sprintf("file '%s' (replaced)%s", name, previewFilter(filter))
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreviewAction(t *testing.T) {
+	dc := NewDataCache()
+	dc.AddLocalValue("msg", "Message", "default", 1, false, false, false, true)
+	dc.AddLocalValue("pw", "Password", "secret", 1, true, false, false, false)
+	dc.AddLocalValue("dir", "Dir", "/tmp/x", 1, false, false, false, false)
+	a := NewActionData("A", "", "desc", "", -1, false, false)
+	a.AddSingleAction("git", []string{"commit", "-m", "%{msg}"}, "", "", "", "", "memory:out|fix", "", "", "", 0, 0, false)
+	a.AddSingleAction("mv", []string{"-f", "%{dir}", "%{pw}"}, "", "file:in.txt", "", "", "append:log.txt", "", "exists:%{dir}", "", 0, 0, false)
+	p := PreviewAction(a, dc)
+	testPreviewContains(t, p, "Command:   git commit -m '<input:msg>'", "Preview:1.0")
+	testPreviewContains(t, p, "Stdout:    memory value 'out'. Filter 'fix'", "Preview:1.1")
+	testPreviewContains(t, p, "Command:   mv -f /tmp/x '<password:pw>'", "Preview:1.2")
+	testPreviewContains(t, p, "Stdin:     file 'in.txt'", "Preview:1.3")
+	testPreviewContains(t, p, "Stdout:    append to file 'log.txt'", "Preview:1.4")
+	testPreviewContains(t, p, "If:        'exists:%{dir}' -> 'exists:/tmp/x' skipped now", "Preview:1.5")
+	lv, _ := dc.GetLocalValue("msg")
+	if lv.inputDone {
+		t.Fatalf("[Preview:1.6]: Preview should not input values")
+	}
+}
+
+func TestPreviewExitRc(t *testing.T) {
+	dc := NewDataCache()
+	p := PreviewAction(NewActionData("A", "", "desc", "", 0, false, false), dc)
+	if strings.Contains(p, "Exit gtool") {
+		t.Fatalf("[PreviewRc:1.0]: RC=0 does not exit gtool\n%s", p)
+	}
+	p = PreviewAction(NewActionData("A", "", "desc", "", 2, false, false), dc)
+	testPreviewContains(t, p, "Exit gtool with RC=2 when complete (headless only)", "PreviewRc:1.1")
+}
+
+func testPreviewContains(t *testing.T, preview, exp, info string) {
+	if !strings.Contains(preview, exp) {
+		t.Fatalf("[%s]: Preview does not contain '%s'\n%s", info, exp, preview)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+var runningActions = NewRunningActions()
+
+/*
+ActionRunner tracks the processes currently running for an action so they can be cancelled.
+There is more than one when commands are piped together.
+*/
+type ActionRunner struct {
+	mu        sync.Mutex
+	action    *MultipleActionData
+	cmds      []*exec.Cmd
+	cancelled bool
+	cancel    chan bool // Closed when the action is cancelled
+}
+
+/*
+RunningActions is the set of actions currently running. An action is identified by
+its MultipleActionData so the same action cannot run twice at the same time.
+*/
+type RunningActions struct {
+	mu      sync.Mutex
+	runners []*ActionRunner // In the order they were started
+}
+
+func NewActionRunner(action *MultipleActionData) *ActionRunner {
+	return &ActionRunner{action: action, cmds: make([]*exec.Cmd, 0), cancelled: false, cancel: make(chan bool)}
+}
+
+/*
+Record a running command. If the action was cancelled before the command started it is killed.
+*/
+func (ar *ActionRunner) addCmd(cmd *exec.Cmd) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	ar.cmds = append(ar.cmds, cmd)
+	if ar.cancelled {
+		killProcessGroup(cmd)
+	}
+}
+
+/*
+The command has completed
+*/
+func (ar *ActionRunner) removeCmd(cmd *exec.Cmd) {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	for i, c := range ar.cmds {
+		if c == cmd {
+			ar.cmds = append(ar.cmds[:i], ar.cmds[i+1:]...)
+			return
+		}
+	}
+}
+
+/*
+Kill the running processes (and their process groups). Remaining commands are skipped.
+*/
+func (ar *ActionRunner) Cancel() {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	if !ar.cancelled {
+		close(ar.cancel)
+	}
+	ar.cancelled = true
+	for _, cmd := range ar.cmds {
+		killProcessGroup(cmd)
+	}
+}
+
+func (ar *ActionRunner) IsCancelled() bool {
+	ar.mu.Lock()
+	defer ar.mu.Unlock()
+	return ar.cancelled
+}
+
+/*
+Wait for the given number of milliseconds. Returns false if the action was cancelled while waiting.
+*/
+func (ar *ActionRunner) Sleep(ms int) bool {
+	select {
+	case <-ar.cancel:
+		return false
+	case <-time.After(time.Duration(ms) * time.Millisecond):
+		return true
+	}
+}
+
+func NewRunningActions() *RunningActions {
+	return &RunningActions{runners: make([]*ActionRunner, 0)}
+}
+
+/*
+Returns an error if the action cannot be started now. Either it is already running or
+it, or one of the running actions, is 'exclusive'.
+*/
+func (ra *RunningActions) CanStart(action *MultipleActionData) error {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	return ra.canStart(action)
+}
+
+func (ra *RunningActions) canStart(action *MultipleActionData) error {
+	for _, r := range ra.runners {
+		if r.action == action {
+			return fmt.Errorf("action '%s' is already running", action.name)
+		}
+		if r.action.exclusive {
+			return fmt.Errorf("action '%s' is running and must run alone", r.action.name)
+		}
+	}
+	if action.exclusive && len(ra.runners) > 0 {
+		return fmt.Errorf("action '%s' must run alone. %d other action(s) running", action.name, len(ra.runners))
+	}
+	return nil
+}
+
+/*
+Register a new runner for the action. The check and the add are done under the same lock.
+*/
+func (ra *RunningActions) Start(action *MultipleActionData) (*ActionRunner, error) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	err := ra.canStart(action)
+	if err != nil {
+		return nil, err
+	}
+	runner := NewActionRunner(action)
+	ra.runners = append(ra.runners, runner)
+	return runner, nil
+}
+
+func (ra *RunningActions) Remove(runner *ActionRunner) {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	for i, r := range ra.runners {
+		if r == runner {
+			ra.runners = append(ra.runners[:i], ra.runners[i+1:]...)
+			return
+		}
+	}
+}
+
+/*
+A copy of the list of runners so the caller does not need the lock
+*/
+func (ra *RunningActions) List() []*ActionRunner {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	resp := make([]*ActionRunner, len(ra.runners))
+	copy(resp, ra.runners)
+	return resp
+}
+
+func (ra *RunningActions) Len() int {
+	ra.mu.Lock()
+	defer ra.mu.Unlock()
+	return len(ra.runners)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunningActionsSameAction(t *testing.T) {
+	ra := NewRunningActions()
+	a1 := NewActionData("a1", "", "", "", -1, false, false)
+	r1, err := ra.Start(a1)
+	if err != nil {
+		t.Fatalf("Start a1 should not fail: %s", err.Error())
+	}
+	_, err = ra.Start(a1)
+	testRunnerErr(t, err, "action 'a1' is already running", "Same:1.0")
+	ra.Remove(r1)
+	if ra.Len() != 0 {
+		t.Fatalf("Same:1.1 Len should be 0 not %d", ra.Len())
+	}
+	_, err = ra.Start(a1)
+	if err != nil {
+		t.Fatalf("Same:1.2 Start a1 after remove should not fail: %s", err.Error())
+	}
+}
+
+func TestRunningActionsConcurrent(t *testing.T) {
+	ra := NewRunningActions()
+	a1 := NewActionData("a1", "", "", "", -1, false, false)
+	a2 := NewActionData("a2", "", "", "", -1, false, false)
+	ra.Start(a1)
+	ra.Start(a2)
+	if ra.Len() != 2 {
+		t.Fatalf("Concurrent:1.0 Len should be 2 not %d", ra.Len())
+	}
+	l := ra.List()
+	if l[0].action != a1 || l[1].action != a2 {
+		t.Fatalf("Concurrent:1.1 List should be in start order")
+	}
+}
+
+func TestRunningActionsExclusive(t *testing.T) {
+	ra := NewRunningActions()
+	a1 := NewActionData("a1", "", "", "", -1, false, false)
+	ex := NewActionData("ex", "", "", "", -1, true, false)
+	r1, _ := ra.Start(a1)
+	_, err := ra.Start(ex)
+	testRunnerErr(t, err, "action 'ex' must run alone. 1 other action(s) running", "Exclusive:1.0")
+	ra.Remove(r1)
+	_, err = ra.Start(ex)
+	if err != nil {
+		t.Fatalf("Exclusive:1.1 Start ex should not fail: %s", err.Error())
+	}
+	err = ra.CanStart(a1)
+	testRunnerErr(t, err, "action 'ex' is running and must run alone", "Exclusive:1.2")
+}
+
+func TestActionRunnerCancel(t *testing.T) {
+	r := NewActionRunner(NewActionData("a1", "", "", "", -1, false, false))
+	if r.IsCancelled() {
+		t.Fatalf("Cancel:1.0 New runner should not be cancelled")
+	}
+	if !r.Sleep(1) {
+		t.Fatalf("Cancel:1.1 Sleep should complete")
+	}
+	r.Cancel()
+	if !r.IsCancelled() {
+		t.Fatalf("Cancel:1.2 Runner should be cancelled")
+	}
+	r.Cancel()
+	if r.Sleep(10000) {
+		t.Fatalf("Cancel:1.3 Sleep should return false when cancelled")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	sa := NewSingleAction("echo", []string{}, "", "", "", "", "", "", "", "", 0, 0, false)
+	if sa.RetryDelay(1) != DEFAULT_RETRY_DELAY_MS {
+		t.Fatalf("Retry:1.0 Default delay should be %d not %d", DEFAULT_RETRY_DELAY_MS, sa.RetryDelay(1))
+	}
+	sa.SetRetry(3, 100, 2)
+	for i, exp := range []int{100, 200, 400} {
+		if sa.RetryDelay(i+1) != exp {
+			t.Fatalf("Retry:1.%d Delay for retry %d should be %d not %d", i+1, i+1, exp, sa.RetryDelay(i+1))
+		}
+	}
+}
+
+func testRunnerErr(t *testing.T, err error, exp, info string) {
+	if err == nil {
+		t.Fatalf("[%s]: Should return error '%s'", info, exp)
+	}
+	if !strings.Contains(err.Error(), exp) {
+		t.Fatalf("[%s]: Actual Error:'%s' != Expected Error:'%s'", info, err.Error(), exp)
+	}
+}
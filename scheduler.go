@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	MIN_SCHEDULE_EVERY_MS = 1000 // The shortest interval for an 'every' schedule
+	SCHEDULER_TICK_MS     = 1000 // How often the scheduler checks for actions that are due
+)
+
+/*
+Scheduler runs the BG_ACTION background actions defined in config.schedule while gtool is open.
+*/
+type Scheduler struct {
+	tasks         []*BackgroundAction
+	notifyChannel chan *NotifyMessage
+	dataCache     *DataCache
+	stop          chan bool
+}
+
+func StartScheduler(tasks []*BackgroundAction, notifyChannel chan *NotifyMessage, dataCache *DataCache) *Scheduler {
+	s := &Scheduler{tasks: tasks, notifyChannel: notifyChannel, dataCache: dataCache, stop: make(chan bool)}
+	now := time.Now()
+	for _, t := range tasks {
+		t.setNextRun(now)
+	}
+	if len(tasks) > 0 {
+		go s.run()
+	}
+	return s
+}
+
+func (s *Scheduler) Stop() {
+	if s != nil {
+		close(s.stop)
+	}
+}
+
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(SCHEDULER_TICK_MS * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case now := <-ticker.C:
+			for _, t := range s.tasks {
+				if t.startIfDue(now) {
+					go s.execTask(t)
+				}
+			}
+		}
+	}
+}
+
+func (s *Scheduler) execTask(t *BackgroundAction) {
+	if s.notifyChannel != nil {
+		s.notifyChannel <- NewNotifyMessage(LOG, t.action, "Scheduled", t.ScheduleDesc(), RC_OK, nil)
+	}
+	rc := execMultipleAction(t.action, s.notifyChannel, s.dataCache)
+	t.setComplete(rc)
+	if s.notifyChannel != nil {
+		s.notifyChannel <- NewNotifyMessage(REFRESH, t.action, "Scheduled action complete", fmt.Sprintf("next:%s", FormatScheduleTime(t.GetNextRun())), rc, nil)
+	}
+}
+
+func (bga *BackgroundAction) setNextRun(from time.Time) {
+	bga.mu.Lock()
+	defer bga.mu.Unlock()
+	bga.nextRun = bga.calcNextRun(from)
+}
+
+func (bga *BackgroundAction) calcNextRun(from time.Time) time.Time {
+	if bga.cron != nil {
+		return bga.cron.Next(from)
+	}
+	if bga.every > 0 {
+		return from.Add(time.Duration(bga.every) * time.Millisecond)
+	}
+	return time.Time{}
+}
+
+/*
+If the action is due (and not still running from last time) mark it as running and
+calculate the next run time.
+*/
+func (bga *BackgroundAction) startIfDue(now time.Time) bool {
+	bga.mu.Lock()
+	defer bga.mu.Unlock()
+	if bga.running || bga.nextRun.IsZero() || now.Before(bga.nextRun) {
+		return false
+	}
+	bga.running = true
+	bga.lastRun = now
+	bga.nextRun = bga.calcNextRun(now)
+	return true
+}
+
+func (bga *BackgroundAction) setComplete(rc int) {
+	bga.mu.Lock()
+	defer bga.mu.Unlock()
+	bga.running = false
+	bga.lastRc = rc
+}
+
+func (bga *BackgroundAction) GetNextRun() time.Time {
+	bga.mu.Lock()
+	defer bga.mu.Unlock()
+	return bga.nextRun
+}
+
+/*
+Returns the next and last run times, the last return code and if it is running now
+*/
+func (bga *BackgroundAction) GetScheduleState() (time.Time, time.Time, int, bool) {
+	bga.mu.Lock()
+	defer bga.mu.Unlock()
+	return bga.nextRun, bga.lastRun, bga.lastRc, bga.running
+}
+
+func FormatScheduleTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
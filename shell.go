@@ -0,0 +1,24 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+const DEFAULT_SHELL = "sh" // Used when "shell": true
+
+/*
+Quote a value so the shell treats it as a single word with no expansion.
+Single quotes in the value are closed, escaped and reopened.
+*/
+func ShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+/*
+Run the script with 'shell -c'. The args are available to the script as $1, $2...
+*/
+func NewShellCommand(shell, script string, args []string) *exec.Cmd {
+	shArgs := append([]string{"-c", script, shell}, args...)
+	return exec.Command(shell, shArgs...)
+}
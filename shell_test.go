@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	testShellQuote(t, "simple", "Quote:1.0")
+	testShellQuote(t, "", "Quote:1.1")
+	testShellQuote(t, "it's", "Quote:1.2")
+	testShellQuote(t, "$(echo x); `id` \"q\" $HOME \\n", "Quote:1.3")
+	testShellQuote(t, "a'b''c'", "Quote:1.4")
+}
+
+func TestShellTemplate(t *testing.T) {
+	dc := NewDataCache()
+	dc.AddLocalValue("msg", "Msg", "a'b; echo x", 1, false, false, false, false)
+	s, err := dc.ShellTemplate("echo %{msg} %{notFound}", nil)
+	if err != nil {
+		t.Fatalf("[Template:1.0]: Should not return error %s", err.Error())
+	}
+	exp := `echo 'a'\''b; echo x' %{notFound}`
+	if s != exp {
+		t.Fatalf("[Template:1.1]: Actual:'%s' != Expected:'%s'", s, exp)
+	}
+}
+
+func testShellQuote(t *testing.T, value, info string) {
+	out, err := NewShellCommand(DEFAULT_SHELL, "printf '%s' "+ShellQuote(value), []string{}).Output()
+	if err != nil {
+		t.Fatalf("[%s]: Should not return error %s", info, err.Error())
+	}
+	if string(out) != value {
+		t.Fatalf("[%s]: Actual:'%s' != Expected:'%s'", info, out, value)
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	STATE_DIR  = "gtool"
+	STATE_FILE = "gtool-state.json"
+)
+
+/*
+The persisted state of a LocalValue with 'persist: true'.
+*/
+type ValueState struct {
+	Value     string `json:"value"`
+	LastValue string `json:"lastValue,omitempty"`
+}
+
+/*
+The state file holds the persisted values for each config file. The key is the absolute
+config file name so different config files can use the same value names.
+*/
+type stateData map[string]map[string]*ValueState
+
+/*
+The state file is in the user's config dir. E.g. ~/.config/gtool/gtool-state.json
+Returns "" if the user's config dir is not defined.
+*/
+func StateFileName() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, STATE_DIR, STATE_FILE)
+}
+
+func readStateFile(stateFile string) (stateData, error) {
+	state := make(stateData)
+	b, err := os.ReadFile(stateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("failed to read state file. %s", err.Error())
+	}
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return nil, fmt.Errorf("state file '%s' is invalid. %s", stateFile, err.Error())
+	}
+	return state, nil
+}
+
+/*
+Restore the values with 'persist: true' that were saved for 'configFile'.
+Password values are never persisted so they are not restored. Values that are not valid
+(see LocalValue.Validate) are not restored and are listed in the error.
+*/
+func (dc *DataCache) LoadState(stateFile, configFile string) error {
+	if stateFile == "" {
+		return nil
+	}
+	state, err := readStateFile(stateFile)
+	if err != nil {
+		return err
+	}
+	values, found := state[configFile]
+	if !found {
+		return nil
+	}
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	invalid := make([]string, 0)
+	for n, vs := range values {
+		lv, found := dc.localVarMap[n]
+		if found && lv.persist && !lv.isPassword && vs != nil {
+			//
+			// The config may have changed since the value was saved. Keep the config value if it is no longer valid.
+			//
+			if vs.Value != "" {
+				if err := lv.Validate(vs.Value); err != nil {
+					invalid = append(invalid, err.Error())
+					continue
+				}
+			}
+			lv._value = vs.Value
+			lv.lastValue = vs.LastValue
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return fmt.Errorf("persisted values not restored. %s", strings.Join(invalid, ". "))
+	}
+	return nil
+}
+
+/*
+Save the values with 'persist: true' for 'configFile'. Values saved for other config files
+are kept. Password values are excluded. Nothing is written if no values are persisted.
+*/
+func (dc *DataCache) SaveState(stateFile, configFile string) error {
+	if stateFile == "" {
+		return nil
+	}
+	values := make(map[string]*ValueState)
+	dc.mu.Lock()
+	for n, lv := range dc.localVarMap {
+		if lv.persist && !lv.isPassword {
+			values[n] = &ValueState{Value: lv._value, LastValue: lv.lastValue}
+		}
+	}
+	dc.mu.Unlock()
+	if len(values) == 0 {
+		return nil
+	}
+	state, err := readStateFile(stateFile)
+	if err != nil {
+		//
+		// Dont fail for ever because of a bad state file. Replace it.
+		//
+		state = make(stateData)
+	}
+	state[configFile] = values
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to create state data. %s", err.Error())
+	}
+	err = os.MkdirAll(filepath.Dir(stateFile), 0700)
+	if err != nil {
+		return fmt.Errorf("failed to create state dir. %s", err.Error())
+	}
+	err = os.WriteFile(stateFile, b, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write state file. %s", err.Error())
+	}
+	return nil
+}
+
+/*
+Save the persisted values for the model. Called when the application exits.
+*/
+func (m *Model) SaveState() error {
+	return m.dataCache.SaveState(m.stateFile, m.fileName)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestPersistState(t *testing.T) {
+	stateFile := "state_test.json"
+	defer os.Remove(stateFile)
+
+	dc := NewDataCache()
+	msg := dc.AddLocalValue("msg", "Message", "default", 1, false, false, false, true)
+	msg.persist = true
+	pw := dc.AddLocalValue("pw", "Password", "", 1, true, false, false, true)
+	pw.persist = true
+	dc.AddLocalValue("tmp", "Temp", "tmp", 1, false, false, false, false)
+
+	msg.SetValue("fix the build")
+	msg.lastValue = "/tmp"
+	pw.SetValue("secret")
+	err := dc.SaveState(stateFile, "/a/config.json")
+	if err != nil {
+		t.Fatalf("[State:1.0]: Should not return error %s", err.Error())
+	}
+	b, _ := os.ReadFile(stateFile)
+	if strings.Contains(string(b), "secret") || strings.Contains(string(b), "\"tmp\"") {
+		t.Fatalf("[State:1.1]: Password and non persisted values should not be saved. Got %s", string(b))
+	}
+
+	dc2 := NewDataCache()
+	msg2 := dc2.AddLocalValue("msg", "Message", "default", 1, false, false, false, true)
+	msg2.persist = true
+	err = dc2.LoadState(stateFile, "/b/config.json")
+	if err != nil || msg2.GetValue() != "default" {
+		t.Fatalf("[State:1.2]: Values from another config file should not be restored. Got %s %v", msg2.GetValue(), err)
+	}
+	err = dc2.LoadState(stateFile, "/a/config.json")
+	if err != nil || msg2.GetValue() != "fix the build" || msg2.lastValue != "/tmp" {
+		t.Fatalf("[State:1.3]: Value should be restored. Got %s %s %v", msg2.GetValue(), msg2.lastValue, err)
+	}
+
+	err = dc2.LoadState("state_test_missing.json", "/a/config.json")
+	if err != nil {
+		t.Fatalf("[State:1.4]: A missing state file is not an error. Got %s", err.Error())
+	}
+	os.WriteFile(stateFile, []byte("{bad"), 0600)
+	err = dc2.LoadState(stateFile, "/a/config.json")
+	if err == nil || !strings.Contains(err.Error(), "is invalid") {
+		t.Fatalf("[State:1.5]: Should return an invalid state file error. Got %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	FILTER_TRIM    = "trim"     // Remove spaces around each line and blank lines
+	FILTER_REPLACE = "replace:" // Replace text. E.g. replace:from:to
+	FILTER_UPPER   = "upper"    // Upper case
+	FILTER_LOWER   = "lower"    // Lower case
+	FILTER_SORT    = "sort"     // Sort the lines
+	FILTER_UNIQ    = "uniq"     // Remove duplicate lines
+	FILTER_JOIN    = "join:"    // Join the lines with a separator. E.g. join:,
+	FILTER_COUNT   = "count"    // The number of lines
+)
+
+/*
+Returns the transform stage for the selector or nil if it is not a transform.
+A transform is applied to all of the output of the stage before it.
+*/
+func newTransformStage(sel string) (func([]byte) ([]byte, error), error) {
+	switch sel {
+	case FILTER_TRIM:
+		return func(input []byte) ([]byte, error) {
+			lines, _ := splitLines(string(input))
+			res := make([]string, 0)
+			for _, l := range lines {
+				l = strings.TrimSpace(l)
+				if l != "" {
+					res = append(res, l)
+				}
+			}
+			return []byte(strings.Join(res, "\n")), nil
+		}, nil
+	case FILTER_UPPER:
+		return func(input []byte) ([]byte, error) {
+			return []byte(strings.ToUpper(string(input))), nil
+		}, nil
+	case FILTER_LOWER:
+		return func(input []byte) ([]byte, error) {
+			return []byte(strings.ToLower(string(input))), nil
+		}, nil
+	case FILTER_SORT:
+		return func(input []byte) ([]byte, error) {
+			lines, nl := splitLines(string(input))
+			sort.Strings(lines)
+			return []byte(joinLines(lines, nl)), nil
+		}, nil
+	case FILTER_UNIQ:
+		return func(input []byte) ([]byte, error) {
+			lines, nl := splitLines(string(input))
+			found := make(map[string]bool)
+			res := make([]string, 0)
+			for _, l := range lines {
+				if !found[l] {
+					found[l] = true
+					res = append(res, l)
+				}
+			}
+			return []byte(joinLines(res, nl)), nil
+		}, nil
+	case FILTER_COUNT:
+		return func(input []byte) ([]byte, error) {
+			lines, _ := splitLines(string(input))
+			return []byte(strconv.Itoa(len(lines))), nil
+		}, nil
+	}
+	if strings.HasPrefix(sel, FILTER_JOIN) {
+		sep := sel[len(FILTER_JOIN):]
+		return func(input []byte) ([]byte, error) {
+			lines, _ := splitLines(string(input))
+			return []byte(strings.Join(lines, sep)), nil
+		}, nil
+	}
+	if strings.HasPrefix(sel, FILTER_REPLACE) {
+		parts := strings.SplitN(sel[len(FILTER_REPLACE):], ":", 2)
+		if len(parts) < 2 || parts[0] == "" {
+			return nil, fmt.Errorf("filter element '%s' must be replace:from:to. E.g. replace:\\r:", sel)
+		}
+		return func(input []byte) ([]byte, error) {
+			return []byte(strings.ReplaceAll(string(input), parts[0], parts[1])), nil
+		}, nil
+	}
+	return nil, nil
+}
+
+/*
+Split the output in to lines. Returns true if the output ended with a new line.
+Empty output has no lines.
+*/
+func splitLines(s string) ([]string, bool) {
+	nl := strings.HasSuffix(s, "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return []string{}, nl
+	}
+	return strings.Split(s, "\n"), nl
+}
+
+/*
+Join the lines with a new line. If 'nl' is true the output ends with a new line.
+*/
+func joinLines(lines []string, nl bool) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	s := strings.Join(lines, "\n")
+	if nl {
+		return s + "\n"
+	}
+	return s
+}
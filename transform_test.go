@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+)
+
+const tdTransform = "  b = 2 \n\na=1\nb = 2\n c=3\t\n"
+
+func TestFilterTransform(t *testing.T) {
+	testCompleteResult(t, "name|trim", []string{tdTransform}, "b = 2\na=1\nb = 2\nc=3", "Transform:1.0")
+	testCompleteResult(t, "name|trim|upper", []string{tdTransform[:5], tdTransform[5:]}, "B = 2\nA=1\nB = 2\nC=3", "Transform:1.1")
+	testCompleteResult(t, "name|ABC|lower", []string{"ABC\nDEF\n"}, "abc", "Transform:1.2")
+	testCompleteResult(t, "name|trim|replace: :", []string{tdTransform}, "b=2\na=1\nb=2\nc=3", "Transform:1.3")
+	testCompleteResult(t, "name|replace:=:: ", []string{"a=1\n"}, "a: 1\n", "Transform:1.4")
+	testCompleteResult(t, "name|trim|sort", []string{tdTransform}, "a=1\nb = 2\nb = 2\nc=3", "Transform:1.5")
+	testCompleteResult(t, "name|trim|uniq", []string{tdTransform}, "b = 2\na=1\nc=3", "Transform:1.6")
+	testCompleteResult(t, "name|trim|sort|uniq|join:, ", []string{tdTransform}, "a=1, b = 2, c=3", "Transform:1.7")
+	testCompleteResult(t, "name|trim|count", []string{tdTransform}, "4", "Transform:1.8")
+	testCompleteResult(t, "name|xyz|count", []string{tdTransform}, "0", "Transform:1.9")
+	testCompleteResult(t, "name|b,,,\n|count", []string{tdTransform}, "2", "Transform:1.10")
+
+	testCompleteResult(t, "name|replace:", []string{tdTransform}, "filter element 'replace:' must be replace:from:to. E.g. replace:\\r:", "Transform:2.0")
+	testCompleteResult(t, "name|replace:a", []string{tdTransform}, "filter element 'replace:a' must be replace:from:to. E.g. replace:\\r:", "Transform:2.1")
+
+	testPipeWriter(t, "trim|sort", []string{tdTransform}, "a=1\nb = 2\nb = 2\nc=3", "Transform:3.0")
+
+	mr, err = NewStringReader("file:test_data/readers_test.data|user.name,=,1,\n|upper|trim", testReaderR, testDataCacheR)
+	if err != nil {
+		t.Fatalf("[Transform:4.0]: Should return nil not: %s", err.Error())
+	}
+	testRead(t, mr, 8, "TESTUSER", "Transform:4.0", 100)
+}
+
+func TestFilterQuoted(t *testing.T) {
+	testCompleteResult(t, "name|'count',,,\n", []string{"a count\nb\n"}, "a count\n", "Quoted:1.0")
+	testCompleteResult(t, "name|'2022-10',,,\n", []string{"2022-10-01 x\n2023-01-01 y\n"}, "2022-10-01 x\n", "Quoted:1.1")
+	testCompleteResult(t, "name|'-1',,,\n", []string{"a\nb -1\nc\n"}, "b -1\n", "Quoted:1.2")
+	testCompleteResult(t, "name|'!x',,,\n", []string{"a !x\nb x\n"}, "a !x\n", "Quoted:1.3")
+	testCompleteResult(t, "name|'json:a',,,\n|'sort'", []string{"json:a b\nsort\n"}, "json:a b\nsort", "Quoted:1.4")
+	testCompleteResult(t, "name|'re:/x/'", []string{"re:/x/\nx\n"}, "re:/x/", "Quoted:1.5")
+	testCompleteResult(t, "name|2022-10", []string{"2022-10-01 x\n"}, "line range in filter element '2022-10' ends before it starts. To select lines containing it use '2022-10'", "Quoted:2.0")
+}
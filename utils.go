@@ -53,9 +53,9 @@ func GetArg(name string) (string, error) {
 	for _, v := range os.Args {
 		vlc := strings.ToLower(v)
 		if strings.HasPrefix(vlc, namelc) {
-			l := 2
+			l := len(namelc)
 			if strings.HasPrefix(vlc, namelc+"=") {
-				l = 3
+				l++
 			}
 			s := v[l:]
 			if len(s) < 1 {
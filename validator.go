@@ -12,6 +12,11 @@ type NodeDef struct {
 	optional bool
 }
 
+const (
+	NT_STRING_OR_BOOL parser.NodeType = iota + 100 // Pseudo type. The node can be a STRING or a BOOL
+	NT_LIST_OR_STRING                              // Pseudo type. The node can be a LIST or a STRING
+)
+
 var (
 	ALT_EXIT = map[string]NodeDef{
 		"title": {
@@ -40,6 +45,21 @@ var (
 		"localConfig": {
 			parser.NT_STRING, true,
 		},
+		"schedule": {
+			parser.NT_LIST, true,
+		},
+	}
+
+	SCHEDULE_DEF = map[string]NodeDef{
+		"action": {
+			parser.NT_STRING, false,
+		},
+		"every": {
+			parser.NT_NUMBER, true,
+		},
+		"cron": {
+			parser.NT_STRING, true,
+		},
 	}
 
 	VALUE_DEF = map[string]NodeDef{
@@ -64,6 +84,27 @@ var (
 		"isFileWatch": {
 			parser.NT_BOOL, true,
 		},
+		"persist": {
+			parser.NT_BOOL, true,
+		},
+		"choices": {
+			NT_LIST_OR_STRING, true,
+		},
+		"type": {
+			parser.NT_STRING, true,
+		},
+		"pattern": {
+			parser.NT_STRING, true,
+		},
+		"maxLen": {
+			parser.NT_NUMBER, true,
+		},
+		"min": {
+			parser.NT_NUMBER, true,
+		},
+		"max": {
+			parser.NT_NUMBER, true,
+		},
 	}
 
 	ACTION_DEF = map[string]NodeDef{
@@ -82,11 +123,35 @@ var (
 		"hide": {
 			parser.NT_STRING, true,
 		},
+		"exclusive": {
+			parser.NT_BOOL, true,
+		},
+		"background": {
+			parser.NT_BOOL, true,
+		},
+		"env": {
+			parser.NT_OBJECT, true,
+		},
+		"confirm": {
+			parser.NT_STRING, true,
+		},
 		"list": {
 			parser.NT_LIST, true,
 		},
 	}
 
+	ACTION_REF_DEF = map[string]NodeDef{
+		"action": {
+			parser.NT_STRING, false,
+		},
+		"if": {
+			parser.NT_STRING, true,
+		},
+		"unless": {
+			parser.NT_STRING, true,
+		},
+	}
+
 	SINGLE_ACTION_DEF = map[string]NodeDef{
 		"cmd": {
 			parser.NT_STRING, false,
@@ -115,9 +180,39 @@ var (
 		"delay": {
 			parser.NT_NUMBER, true,
 		},
+		"timeout": {
+			parser.NT_NUMBER, true,
+		},
 		"ignoreError": {
 			parser.NT_BOOL, true,
 		},
+		"retries": {
+			parser.NT_NUMBER, true,
+		},
+		"retryDelay": {
+			parser.NT_NUMBER, true,
+		},
+		"retryBackoff": {
+			parser.NT_NUMBER, true,
+		},
+		"env": {
+			parser.NT_OBJECT, true,
+		},
+		"envFile": {
+			parser.NT_STRING, true,
+		},
+		"shell": {
+			NT_STRING_OR_BOOL, true,
+		},
+		"pipe": {
+			parser.NT_BOOL, true,
+		},
+		"if": {
+			parser.NT_STRING, true,
+		},
+		"unless": {
+			parser.NT_STRING, true,
+		},
 	}
 )
 
@@ -140,8 +235,8 @@ func ValidateNode(def map[string]NodeDef, node parser.NodeC, desc string) (strin
 			return fmt.Sprintf("%s Node %scontains invalid node '%s'", desc, nn, name), false
 		}
 		founds[name] = true
-		if d.nType != n.GetNodeType() {
-			return fmt.Sprintf("%s Node '%s' should be of type '%s'", desc, name, parser.GetNodeTypeName(d.nType)), false
+		if !nodeTypeMatches(d.nType, n.GetNodeType()) {
+			return fmt.Sprintf("%s Node '%s' should be of type '%s'", desc, name, nodeTypeName(d.nType)), false
 		}
 		if !d.optional {
 			switch d.nType {
@@ -160,3 +255,23 @@ func ValidateNode(def map[string]NodeDef, node parser.NodeC, desc string) (strin
 	}
 	return "", true
 }
+
+func nodeTypeMatches(defType, nType parser.NodeType) bool {
+	switch defType {
+	case NT_STRING_OR_BOOL:
+		return nType == parser.NT_STRING || nType == parser.NT_BOOL
+	case NT_LIST_OR_STRING:
+		return nType == parser.NT_LIST || nType == parser.NT_STRING
+	}
+	return defType == nType
+}
+
+func nodeTypeName(defType parser.NodeType) string {
+	switch defType {
+	case NT_STRING_OR_BOOL:
+		return fmt.Sprintf("%s or %s", parser.GetNodeTypeName(parser.NT_STRING), parser.GetNodeTypeName(parser.NT_BOOL))
+	case NT_LIST_OR_STRING:
+		return fmt.Sprintf("%s or %s", parser.GetNodeTypeName(parser.NT_LIST), parser.GetNodeTypeName(parser.NT_STRING))
+	}
+	return parser.GetNodeTypeName(defType)
+}
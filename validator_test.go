@@ -30,6 +30,24 @@ var (
 	ignoreError = []byte(`{
 		"cmd": "fred", "ignoreError": 1
 	}`)
+	shellNum = []byte(`{
+		"cmd": "fred", "shell": 1
+	}`)
+	shellBool = []byte(`{
+		"cmd": "fred", "shell": true
+	}`)
+	shellStr = []byte(`{
+		"cmd": "fred", "shell": "bash"
+	}`)
+	choicesList = []byte(`{
+		"desc": "Branch", "choices": ["main", "dev"]
+	}`)
+	choicesStr = []byte(`{
+		"desc": "Branch", "choices": "memory:branches"
+	}`)
+	choicesNum = []byte(`{
+		"desc": "Branch", "choices": 1
+	}`)
 )
 
 const (
@@ -45,6 +63,12 @@ func TestValidator(t *testing.T) {
 	testValidator(t, "cmd2", cmd2, SINGLE_ACTION_DEF, DONT_VALIDATE, "Node 'cmd' is missing")
 	testValidator(t, "cmd1", cmd1, SINGLE_ACTION_DEF, DONT_VALIDATE, "'cmd' must have a value")
 	testValidator(t, "ace", ace, SINGLE_ACTION_DEF, VALIDATE, "")
+	testValidator(t, "shellNum", shellNum, SINGLE_ACTION_DEF, DONT_VALIDATE, "'shell' should be of type 'STRING or BOOL'")
+	testValidator(t, "shellBool", shellBool, SINGLE_ACTION_DEF, VALIDATE, "")
+	testValidator(t, "shellStr", shellStr, SINGLE_ACTION_DEF, VALIDATE, "")
+	testValidator(t, "choicesList", choicesList, VALUE_DEF, VALIDATE, "")
+	testValidator(t, "choicesStr", choicesStr, VALUE_DEF, VALIDATE, "")
+	testValidator(t, "choicesNum", choicesNum, VALUE_DEF, DONT_VALIDATE, "'choices' should be of type 'LIST or STRING'")
 }
 
 func testValidator(t *testing.T, id string, json []byte, def map[string]NodeDef, validateExp bool, msgExp string) {
@@ -52,7 +76,7 @@ func testValidator(t *testing.T, id string, json []byte, def map[string]NodeDef,
 	if err != nil {
 		t.Fatalf("Failed Parse:%s", err.Error())
 	}
-	s, v := ValidateNode(SINGLE_ACTION_DEF, n, fmt.Sprintf("Test %s:", id))
+	s, v := ValidateNode(def, n, fmt.Sprintf("Test %s:", id))
 
 	if v != validateExp {
 		if validateExp {
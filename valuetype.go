@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	VT_STRING = "string"
+	VT_INT    = "int"
+	VT_BOOL   = "bool"
+	VT_URL    = "url"
+	VT_PATH   = "path"
+	VT_EMAIL  = "email"
+)
+
+var valueTypes = []string{VT_STRING, VT_INT, VT_BOOL, VT_URL, VT_PATH, VT_EMAIL}
+
+/*
+Set the type of the value and the optional pattern (a regular expression) and maximum length.
+An empty type is a string. maxLen 0 is no maximum.
+*/
+func (lv *LocalValue) SetType(valueType, pattern string, maxLen int) error {
+	if valueType == "" {
+		valueType = VT_STRING
+	}
+	valid := false
+	for _, vt := range valueTypes {
+		if vt == valueType {
+			valid = true
+		}
+	}
+	if !valid {
+		return fmt.Errorf("type '%s' is not one of %s", valueType, strings.Join(valueTypes, ", "))
+	}
+	lv.valueType = valueType
+	lv.pattern = nil
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("pattern '%s' is invalid. %s", pattern, err.Error())
+		}
+		lv.pattern = re
+	}
+	if maxLen > 0 && maxLen < lv.minLen {
+		return fmt.Errorf("maxLen %d is less than minLen %d", maxLen, lv.minLen)
+	}
+	lv.maxLen = maxLen
+	return nil
+}
+
+/*
+Set the minimum and maximum for an int value. nil is no limit.
+*/
+func (lv *LocalValue) SetRange(min, max *float64) error {
+	if (min != nil || max != nil) && lv.valueType != VT_INT {
+		return fmt.Errorf("min and max can only be used with type '%s'", VT_INT)
+	}
+	if min != nil && max != nil && *min > *max {
+		return fmt.Errorf("min %v is greater than max %v", *min, *max)
+	}
+	lv.min = min
+	lv.max = max
+	return nil
+}
+
+func (lv *LocalValue) IsBool() bool {
+	return lv.valueType == VT_BOOL
+}
+
+/*
+Returns nil if 's' is a valid value. Otherwise the error says why so it can be shown to the user.
+*/
+func (lv *LocalValue) Validate(s string) error {
+	if len(strings.TrimSpace(s)) < lv.minLen {
+		return fmt.Errorf("%s must be at least %d chars", lv.desc, lv.minLen)
+	}
+	if lv.maxLen > 0 && len(s) > lv.maxLen {
+		return fmt.Errorf("%s must be at most %d chars", lv.desc, lv.maxLen)
+	}
+	if !lv.IsChoice(s) {
+		return fmt.Errorf("%s must be one of %s", lv.desc, strings.Join(lv.choices, ", "))
+	}
+	switch lv.valueType {
+	case VT_INT:
+		i, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return fmt.Errorf("%s must be a whole number", lv.desc)
+		}
+		if lv.min != nil && float64(i) < *lv.min {
+			return fmt.Errorf("%s must be at least %v", lv.desc, *lv.min)
+		}
+		if lv.max != nil && float64(i) > *lv.max {
+			return fmt.Errorf("%s must be at most %v", lv.desc, *lv.max)
+		}
+	case VT_BOOL:
+		if _, err := strconv.ParseBool(s); err != nil {
+			return fmt.Errorf("%s must be true or false", lv.desc)
+		}
+	case VT_URL:
+		u, err := url.ParseRequestURI(s)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("%s must be a URL. E.g. https://host/path", lv.desc)
+		}
+	case VT_PATH:
+		if strings.ContainsRune(s, 0) || strings.TrimSpace(s) != s {
+			return fmt.Errorf("%s must be a path without leading or trailing spaces", lv.desc)
+		}
+	case VT_EMAIL:
+		a, err := mail.ParseAddress(s)
+		if err != nil || a.Address != s {
+			return fmt.Errorf("%s must be an email address. E.g. name@host.com", lv.desc)
+		}
+	}
+	if lv.pattern != nil && !lv.pattern.MatchString(s) {
+		return fmt.Errorf("%s must match the pattern '%s'", lv.desc, lv.pattern.String())
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValueTypes(t *testing.T) {
+	dc := NewDataCache()
+	lv := dc.AddLocalValue("v", "Value", "", 1, false, false, false, true)
+	testValueValid(t, lv, "anything", "", "Type:1.0")
+	testValueValid(t, lv, " ", "at least 1 chars", "Type:1.1")
+
+	lv.SetType(VT_INT, "", 0)
+	min := 1.0
+	max := 10.0
+	err := lv.SetRange(&min, &max)
+	if err != nil {
+		t.Fatalf("[Type:2.0]: Should not return error %s", err.Error())
+	}
+	testValueValid(t, lv, "5", "", "Type:2.1")
+	testValueValid(t, lv, "5.5", "whole number", "Type:2.2")
+	testValueValid(t, lv, "0", "at least 1", "Type:2.3")
+	testValueValid(t, lv, "11", "at most 10", "Type:2.4")
+
+	lv.SetType(VT_BOOL, "", 0)
+	testValueValid(t, lv, "true", "", "Type:3.0")
+	testValueValid(t, lv, "yes", "true or false", "Type:3.1")
+
+	lv.SetType(VT_URL, "", 0)
+	testValueValid(t, lv, "https://host:8080/path", "", "Type:4.0")
+	testValueValid(t, lv, "host/path", "must be a URL", "Type:4.1")
+
+	lv.SetType(VT_EMAIL, "", 0)
+	testValueValid(t, lv, "fred@host.com", "", "Type:5.0")
+	testValueValid(t, lv, "Fred <fred@host.com>", "email address", "Type:5.1")
+
+	lv.SetType(VT_PATH, "", 0)
+	testValueValid(t, lv, "/tmp/x y", "", "Type:6.0")
+	testValueValid(t, lv, "/tmp/x ", "path without", "Type:6.1")
+
+	lv.SetType(VT_STRING, "^[a-z]+-[0-9]+$", 6)
+	testValueValid(t, lv, "abc-12", "", "Type:7.0")
+	testValueValid(t, lv, "abc-123", "at most 6 chars", "Type:7.1")
+	testValueValid(t, lv, "abc12", "must match the pattern", "Type:7.2")
+}
+
+func TestValueTypeErrors(t *testing.T) {
+	dc := NewDataCache()
+	lv := dc.AddLocalValue("v", "Value", "", 2, false, false, false, true)
+	err := lv.SetType("float", "", 0)
+	if err == nil || !strings.Contains(err.Error(), "is not one of") {
+		t.Fatalf("[TypeErr:1.0]: Should return invalid type error. Got %v", err)
+	}
+	err = lv.SetType(VT_STRING, "[a-", 0)
+	if err == nil || !strings.Contains(err.Error(), "pattern '[a-' is invalid") {
+		t.Fatalf("[TypeErr:1.1]: Should return invalid pattern error. Got %v", err)
+	}
+	err = lv.SetType(VT_STRING, "", 1)
+	if err == nil || !strings.Contains(err.Error(), "less than minLen") {
+		t.Fatalf("[TypeErr:1.2]: Should return maxLen error. Got %v", err)
+	}
+	min := 1.0
+	err = lv.SetRange(&min, nil)
+	if err == nil || !strings.Contains(err.Error(), "only be used with type 'int'") {
+		t.Fatalf("[TypeErr:1.3]: Should return min max type error. Got %v", err)
+	}
+}
+
+func testValueValid(t *testing.T, lv *LocalValue, s, expErr, info string) {
+	err := lv.Validate(s)
+	if expErr == "" {
+		if err != nil {
+			t.Fatalf("[%s]: '%s' should be valid. Got %s", info, s, err.Error())
+		}
+		return
+	}
+	if err == nil || !strings.Contains(err.Error(), expErr) {
+		t.Fatalf("[%s]: '%s' should return error containing '%s'. Got %v", info, s, expErr, err)
+	}
+}
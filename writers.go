@@ -20,16 +20,23 @@ type ClipContent interface {
 	GetContent() string
 }
 
+// Writers keep the last part of a line, or all of the output if the filter needs it (E.g. json:),
+// until Complete is called
+type Complete interface {
+	Complete() error
+}
+
 // Write stdout or stderr to stdout or stderr
 type SysoutWriter struct {
-	prefix string //  prefix is prepended to any output line
-	filter string //  filter filters the lines written (see README.md)
+	prefix string        //  prefix is prepended to any output line
+	filter *WriterFilter //  filter filters the lines written (see README.md)
+	out    io.Writer     //  If not nil output is written here (without the prefix) instead of stdout
 }
 
 // Write stdout or stderr to a file
 type FileWriter struct {
 	fileName string
-	filter   string        // filter filters the lines written (see README.md)
+	filter   *WriterFilter // filter filters the lines written (see README.md)
 	password string        // If the file requires encryption then this is NOT ""
 	file     *os.File      // The file handle
 	canWrite bool          // flag indicates that io can be written to the file
@@ -40,7 +47,7 @@ type FileWriter struct {
 // Write stdout or stderr to memory cache
 type CacheWriter struct {
 	name      string          // Used as the name for the cache
-	filter    string          // filter filters the lines written (see README.md)
+	filter    *WriterFilter   // filter filters the lines written (see README.md)
 	cacheType ENUM_MEM_TYPE   // Properties of the cache entry.
 	sb        strings.Builder // The text in the cache
 }
@@ -48,9 +55,12 @@ type CacheWriter struct {
 var _ Encrypted = (*CacheWriter)(nil)
 var _ ClipContent = (*CacheWriter)(nil)
 var _ Reset = (*CacheWriter)(nil)
+var _ Complete = (*CacheWriter)(nil)
+var _ Complete = (*SysoutWriter)(nil)
+var _ Complete = (*FileWriter)(nil)
 
 type HttpPostWriter struct {
-	filter    string        // filter filters the lines written (see README.md)
+	filter    *WriterFilter // filter filters the lines written (see README.md)
 	cacheType ENUM_MEM_TYPE // Properties of the cache entry.
 	url       string
 	sb        strings.Builder // The text in the cache
@@ -69,13 +79,13 @@ func NewWriter(outDef, key string, defaultStdOut, defaultStdErr *SysoutWriter, d
 		if filter == "" {
 			return defaultStdOut
 		}
-		return NewSysoutWriter(filter, defaultStdOut.prefix)
+		return NewSysoutWriterTo(filter, defaultStdOut.prefix, defaultStdOut.out)
 	}
 	var err error
 	var fn string
 	fn, typ, found := PrefixMatch(name, HTTP_PREF, HTTP_TYPE)
 	if found {
-		return &HttpPostWriter{url: fn, filter: filter, cacheType: typ}
+		return &HttpPostWriter{url: fn, filter: NewWriterFilter(filter), cacheType: typ}
 	}
 
 	fn, typ, found = PrefixMatch(name, CLIP_BOARD_PREF, CLIP_TYPE)
@@ -113,7 +123,7 @@ func NewWriter(outDef, key string, defaultStdOut, defaultStdErr *SysoutWriter, d
 		defaultStdErr.Write([]byte(fmt.Sprintf("Failed to create file writer %s. %s", fn, err.Error())))
 		return defaultStdOut
 	}
-	return &FileWriter{fileName: fn, password: key, file: f, filter: filter, canWrite: true, stdOut: defaultStdOut, stdErr: defaultStdErr}
+	return &FileWriter{fileName: fn, password: key, file: f, filter: NewWriterFilter(filter), canWrite: true, stdOut: defaultStdOut, stdErr: defaultStdErr}
 }
 
 func (hpw *HttpPostWriter) Write(p []byte) (n int, err error) {
@@ -121,7 +131,11 @@ func (hpw *HttpPostWriter) Write(p []byte) (n int, err error) {
 }
 
 func (hpw *HttpPostWriter) Post() error {
-	rc, err := HttpPost(hpw.url, "text/plain", hpw.sb.String())
+	data, err := hpw.filter.Apply([]byte(hpw.sb.String()))
+	if err != nil {
+		return err
+	}
+	rc, err := HttpPost(hpw.url, "text/plain", string(data))
 	if err != nil {
 		return err
 	}
@@ -133,42 +147,65 @@ func (hpw *HttpPostWriter) Post() error {
 
 func NewHttpPostWriter(url, filter string, prefix string) *HttpPostWriter {
 	var sb strings.Builder
-	return &HttpPostWriter{url: url, filter: filter, sb: sb}
+	return &HttpPostWriter{url: url, filter: NewWriterFilter(filter), sb: sb}
 }
 
 func NewSysoutWriter(filter string, prefix string) *SysoutWriter {
-	return &SysoutWriter{filter: filter, prefix: prefix}
+	return &SysoutWriter{filter: NewWriterFilter(filter), prefix: prefix, out: nil}
+}
+
+func NewSysoutWriterTo(filter string, prefix string, out io.Writer) *SysoutWriter {
+	return &SysoutWriter{filter: NewWriterFilter(filter), prefix: prefix, out: out}
 }
 
 func (mw *SysoutWriter) Write(p []byte) (n int, err error) {
 	pLen := len(p)
-	if mw.filter != "" {
-		p, err = Filter(p, mw.filter)
-		if err != nil {
-			return 0, err
-		}
+	p, err = mw.filter.Write(p)
+	if err != nil {
+		return 0, err
+	}
+	err = mw.write(p)
+	if err != nil {
+		return 0, err
 	}
-	fmt.Printf("%s%s%s", mw.prefix, string(p), RESET)
 	return pLen, nil
 }
 
+func (mw *SysoutWriter) write(p []byte) error {
+	if len(p) == 0 {
+		return nil
+	}
+	if mw.out != nil {
+		_, err := mw.out.Write(p)
+		return err
+	}
+	fmt.Printf("%s%s%s", mw.prefix, string(p), RESET)
+	return nil
+}
+
+func (mw *SysoutWriter) Complete() error {
+	p, err := mw.filter.Complete()
+	if err != nil {
+		return err
+	}
+	return mw.write(p)
+}
+
 func NewCacheWriter(name string, cacheType ENUM_MEM_TYPE) (*CacheWriter, error) {
 	cn, cf := splitNameFilter(name)
 	if cn == "" {
 		return nil, fmt.Errorf("memory (cache) writer must have a name")
 	}
 	var sb strings.Builder
-	cw := &CacheWriter{name: cn, filter: cf, cacheType: cacheType, sb: sb}
+	cw := &CacheWriter{name: cn, filter: NewWriterFilter(cf), cacheType: cacheType, sb: sb}
 	return cw, nil
 }
 
 func (cw *CacheWriter) Write(p []byte) (n int, err error) {
 	pLen := len(p)
-	if cw.filter != "" {
-		p, err = Filter(p, cw.filter)
-		if err != nil {
-			return 0, err
-		}
+	p, err = cw.filter.Write(p)
+	if err != nil {
+		return 0, err
 	}
 	if len(p) > 0 {
 		np, errp := cw.sb.Write(p)
@@ -179,6 +216,15 @@ func (cw *CacheWriter) Write(p []byte) (n int, err error) {
 	return pLen, nil
 }
 
+func (cw *CacheWriter) Complete() error {
+	p, err := cw.filter.Complete()
+	if err != nil {
+		return err
+	}
+	cw.sb.Write(p)
+	return nil
+}
+
 func (cw *CacheWriter) SaveToEncryptedFile(key string) error {
 	d, err := EncryptData([]byte(key), []byte(cw.GetContent()))
 	if err != nil {
@@ -205,6 +251,7 @@ func (cw *CacheWriter) ShouldClip() bool {
 
 func (cw *CacheWriter) Reset() {
 	cw.sb.Reset()
+	cw.filter.Reset()
 }
 
 func PrefixMatch(s string, pref string, typ ENUM_MEM_TYPE) (string, ENUM_MEM_TYPE, bool) {
@@ -222,15 +269,21 @@ func (fw *FileWriter) Close() error {
 	return nil
 }
 
+func (fw *FileWriter) Complete() error {
+	p, err := fw.filter.Complete()
+	if err != nil || len(p) == 0 {
+		return err
+	}
+	_, err = fw.file.Write(p)
+	return err
+}
+
 func (fw *FileWriter) Write(p []byte) (n int, err error) {
 	if fw.canWrite {
 		pLen := len(p)
-		if fw.filter != "" {
-			p, err = Filter(p, fw.filter)
-			if err != nil {
-				return 0, err
-			}
-
+		p, err = fw.filter.Write(p)
+		if err != nil {
+			return 0, err
 		}
 		_, err = fw.file.Write(p)
 		if err != nil {